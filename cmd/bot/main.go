@@ -10,6 +10,7 @@ import (
 	"github.com/rotaria-smp/rotaria-bot/internal/mcbridge"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/config"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
 	"github.com/rotaria-smp/rotaria-bot/internal/websocket"
 	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
 )
@@ -42,14 +43,25 @@ func main() {
 		logging.L().Info("blacklist loaded", "count", len(bl.Words()))
 	}
 
-	wlStore, err := whitelist.Open(cfg.DBPath)
+	db, err := sqldb.Open(cfg.DBDriver, cfg.DBDSN())
 	if err != nil {
-		logging.L().Error("whitelist store open failed", "err", err, "path", cfg.DBPath)
+		logging.L().Error("database open failed", "err", err, "driver", cfg.DBDriver)
+		return
+	}
+	defer db.Close()
+
+	wlStore, err := whitelist.New(db)
+	if err != nil {
+		logging.L().Error("whitelist store open failed", "err", err, "driver", cfg.DBDriver)
 		return
 	}
 
 	bridge := mcbridge.New(nil)
-	app := discord.NewApp(sess, cfg, bridge, wlStore, bl)
+	app, err := discord.NewApp(sess, cfg, bridge, db, wlStore, bl)
+	if err != nil {
+		logging.L().Error("app init failed", "err", err)
+		return
+	}
 	if err := app.Register(); err != nil {
 		logging.L().Error("command register failed", "err", err)
 		return
@@ -65,6 +77,8 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	app.Start(ctx)
+
 	go func() {
 		if err := wsServer.Start(); err != nil {
 			logging.L().Error("websocket server error", "err", err)
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// options are the flags shared by every database command.
+type options struct {
+	driver string
+	dsn    string
+	dryRun bool
+	output string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.driver, "driver", envOr("DB_DRIVER", "sqlite"), "database driver (sqlite or postgres)")
+	fs.StringVar(&o.dsn, "db", "", "sqlite path or postgres connection string (default $DB_PATH or $DATABASE_URL)")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "print changes without writing them")
+	fs.StringVar(&o.output, "output", "table", "report format (table or json)")
+}
+
+// open opens the database. A dry run does not create missing tables.
+func (o *options) open() (*sqldb.DB, *whitelist.SQLStore, error) {
+	db, err := openDB(o.driver, o.dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open %s database: %w", o.driver, err)
+	}
+	if o.dryRun {
+		return db, whitelist.Attach(db), nil
+	}
+	st, err := whitelist.New(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+	return db, st, nil
+}
+
+func openDB(driver, dsn string) (*sqldb.DB, error) {
+	if dsn == "" {
+		if d, _ := sqldb.ParseDialect(driver); d == sqldb.Postgres {
+			dsn = os.Getenv("DATABASE_URL")
+		} else {
+			dsn = envOr("DB_PATH", "./database.db")
+		}
+	}
+	return sqldb.Open(driver, dsn)
+}
+
+// change is one row-level action in a report. apply is nil for
+// informational rows that never write.
+type change struct {
+	Action    string `json:"action"`
+	DiscordID string `json:"discord_id,omitempty"`
+	UUID      string `json:"minecraft_uuid,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+
+	apply func(ctx context.Context) error
+}
+
+type report struct {
+	Command string         `json:"command"`
+	DryRun  bool           `json:"dry_run"`
+	Changes []change       `json:"changes"`
+	Summary map[string]int `json:"summary"`
+	Errors  []string       `json:"errors,omitempty"`
+}
+
+// finish applies the changes unless this is a dry run, then prints the report.
+func (o *options) finish(ctx context.Context, name string, changes []change) error {
+	rep := report{Command: name, DryRun: o.dryRun, Changes: changes, Summary: map[string]int{}}
+	for _, c := range changes {
+		rep.Summary[c.Action]++
+		if o.dryRun || c.apply == nil {
+			continue
+		}
+		if err := c.apply(ctx); err != nil {
+			rep.Errors = append(rep.Errors, fmt.Sprintf("%s %s: %v", c.Action, c.Username, err))
+		}
+	}
+	if err := o.print(rep); err != nil {
+		return err
+	}
+	if len(rep.Errors) > 0 {
+		return fmt.Errorf("%d change(s) failed", len(rep.Errors))
+	}
+	return nil
+}
+
+func (o *options) print(rep report) error {
+	if rep.Changes == nil {
+		rep.Changes = []change{}
+	}
+	if o.output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tDISCORD ID\tUUID\tUSERNAME\tDETAIL")
+	for _, c := range rep.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Action, c.DiscordID, c.UUID, c.Username, c.Detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	mode := "applied"
+	if rep.DryRun {
+		mode = "dry run, nothing written"
+	}
+	fmt.Printf("\n%s (%s):", rep.Command, mode)
+	if len(rep.Summary) == 0 {
+		fmt.Print(" no changes")
+	}
+	actions := make([]string, 0, len(rep.Summary))
+	for action := range rep.Summary {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Printf(" %s=%d", action, rep.Summary[action])
+	}
+	fmt.Println()
+	for _, e := range rep.Errors {
+		fmt.Println("error:", e)
+	}
+	return nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
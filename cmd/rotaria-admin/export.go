@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+func runExport(args []string) error {
+	fs := newFlagSet("export")
+	var o options
+	o.register(fs)
+	out := fs.String("file", "whitelist.json", "destination; a .csv extension writes CSV, anything else whitelist.json")
+	_ = fs.Parse(args)
+
+	db, st, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	entries, err := st.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	changes := make([]change, 0, len(entries))
+	for _, e := range entries {
+		changes = append(changes, change{Action: "export", DiscordID: e.DiscordID, UUID: e.MinecraftUUID, Username: e.Username})
+	}
+	if !o.dryRun {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if isCSV(*out) {
+			err = writeCSV(f, entries)
+		} else {
+			err = writeWhitelistJSON(f, entries)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("write %s: %w", *out, err)
+		}
+	}
+	return o.finish(ctx, "export "+*out, changes)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// WLJsonEntry is one entry of the Minecraft server's whitelist.json.
+type WLJsonEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+var csvHeader = []string{"discord_id", "minecraft_uuid", "username"}
+
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+func loadWhitelistJSON(path string) ([]WLJsonEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var entries []WLJsonEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func loadCSV(path string) ([]whitelist.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	var out []whitelist.Entry
+	for n, row := range rows {
+		if n == 0 && len(row) > 0 && row[0] == csvHeader[0] {
+			continue
+		}
+		if len(row) < 3 {
+			return nil, fmt.Errorf("%s line %d: want %d columns, got %d", path, n+1, len(csvHeader), len(row))
+		}
+		out = append(out, whitelist.Entry{
+			DiscordID:     strings.TrimSpace(row[0]),
+			MinecraftUUID: strings.TrimSpace(row[1]),
+			Username:      strings.TrimSpace(row[2]),
+		})
+	}
+	return out, nil
+}
+
+func writeCSV(w io.Writer, entries []whitelist.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{e.DiscordID, e.MinecraftUUID, e.Username}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeWhitelistJSON(w io.Writer, entries []whitelist.Entry) error {
+	out := make([]WLJsonEntry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, WLJsonEntry{UUID: hyphenate(e.MinecraftUUID), Name: e.Username})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// hyphenate formats a 32 character UUID the way whitelist.json stores it.
+func hyphenate(uuid string) string {
+	u := whitelist.NormalizeUUID(uuid)
+	if len(u) != 32 {
+		return uuid
+	}
+	return u[0:8] + "-" + u[8:12] + "-" + u[12:16] + "-" + u[16:20] + "-" + u[20:]
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+func runImport(args []string) error {
+	fs := newFlagSet("import")
+	var o options
+	o.register(fs)
+	file := fs.String("file", "whitelist.json", "whitelist.json or CSV (discord_id,minecraft_uuid,username)")
+	_ = fs.Parse(args)
+
+	db, st, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var changes []change
+	if isCSV(*file) {
+		rows, err := loadCSV(*file)
+		if err != nil {
+			return err
+		}
+		changes, err = planCSVImport(ctx, st, rows)
+		if err != nil {
+			return err
+		}
+	} else {
+		entries, err := loadWhitelistJSON(*file)
+		if err != nil {
+			return err
+		}
+		changes, err = planJSONImport(ctx, st, entries)
+		if err != nil {
+			return err
+		}
+	}
+	return o.finish(ctx, "import", changes)
+}
+
+// planJSONImport updates UUIDs and usernames of existing rows. whitelist.json
+// has no Discord IDs, so players without a row are only reported.
+func planJSONImport(ctx context.Context, st whitelist.Store, entries []WLJsonEntry) ([]change, error) {
+	var changes []change
+	for _, e := range entries {
+		uuid := whitelist.NormalizeUUID(e.UUID)
+		name := strings.TrimSpace(e.Name)
+		if name == "" || uuid == "" {
+			changes = append(changes, change{Action: "skip", UUID: e.UUID, Username: e.Name, Detail: "empty name/uuid"})
+			continue
+		}
+
+		row, err := st.GetByUUID(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			if row, err = st.GetByUsername(ctx, name); err != nil {
+				return nil, err
+			}
+		}
+		if row == nil {
+			changes = append(changes, change{Action: "skip", UUID: uuid, Username: name, Detail: "no database row to link"})
+			continue
+		}
+		if row.MinecraftUUID == uuid && row.Username == name {
+			continue
+		}
+
+		discordID := row.DiscordID
+		changes = append(changes, change{
+			Action:    "update",
+			DiscordID: discordID,
+			UUID:      uuid,
+			Username:  name,
+			Detail:    fmt.Sprintf("was %s / %s", row.MinecraftUUID, row.Username),
+			apply: func(ctx context.Context) error {
+				return st.UpdateUser(ctx, discordID, uuid, name)
+			},
+		})
+	}
+	return changes, nil
+}
+
+// planCSVImport adds new rows and updates rows whose Discord ID already exists.
+func planCSVImport(ctx context.Context, st whitelist.Store, rows []whitelist.Entry) ([]change, error) {
+	var changes []change
+	for _, r := range rows {
+		r.MinecraftUUID = whitelist.NormalizeUUID(r.MinecraftUUID)
+		if r.DiscordID == "" || r.MinecraftUUID == "" || r.Username == "" {
+			changes = append(changes, change{Action: "skip", DiscordID: r.DiscordID, UUID: r.MinecraftUUID, Username: r.Username, Detail: "empty column"})
+			continue
+		}
+
+		existing, err := st.GetByDiscord(ctx, r.DiscordID)
+		if err != nil {
+			return nil, err
+		}
+		r := r
+		switch {
+		case existing == nil:
+			changes = append(changes, change{
+				Action: "add", DiscordID: r.DiscordID, UUID: r.MinecraftUUID, Username: r.Username,
+				apply: func(ctx context.Context) error {
+					err := st.Add(ctx, r.DiscordID, r.MinecraftUUID, r.Username)
+					var conflict *whitelist.ConflictError
+					if errors.As(err, &conflict) {
+						return fmt.Errorf("%w; held by discord %s", conflict.Err, conflict.Entry.DiscordID)
+					}
+					return err
+				},
+			})
+		case existing.MinecraftUUID != r.MinecraftUUID || existing.Username != r.Username:
+			changes = append(changes, change{
+				Action: "update", DiscordID: r.DiscordID, UUID: r.MinecraftUUID, Username: r.Username,
+				Detail: fmt.Sprintf("was %s / %s", existing.MinecraftUUID, existing.Username),
+				apply: func(ctx context.Context) error {
+					return st.UpdateUser(ctx, r.DiscordID, r.MinecraftUUID, r.Username)
+				},
+			})
+		}
+	}
+	return changes, nil
+}
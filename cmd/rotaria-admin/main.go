@@ -0,0 +1,64 @@
+// Command rotaria-admin performs maintenance on the bot's whitelist database.
+//
+//	rotaria-admin <command> [flags]
+//
+// Every command that writes accepts -dry-run and prints the changes it would
+// make as a table or JSON (-output).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"import":        {"import whitelist.json or CSV into the database", runImport},
+	"export":        {"export the database as whitelist.json or CSV", runExport},
+	"verify":        {"check rows for malformed or duplicate data", runVerify},
+	"resolve-uuids": {"fill missing or hyphenated UUIDs through the Mojang API", runResolveUUIDs},
+	"prune":         {"remove rows missing from whitelist.json or the guild", runPrune},
+	"migrate":       {"create the schema, or copy rows to another database", runMigrate},
+	"restore":       {"replace a sqlite database with a verified backup", runRestore},
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: rotaria-admin <command> [flags]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", n, commands[n].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun 'rotaria-admin <command> -h' for flags")
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("rotaria-admin "+name, flag.ExitOnError)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/approval"
+	"github.com/rotaria-smp/rotaria-bot/internal/audit"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/panels"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// copyTables lists every table the bot creates, in the order they are
+// copied.
+var copyTables = []string{
+	"whitelist",
+	"applications", "application_votes", "application_cooldowns", "application_transcripts",
+	"approval_sagas", "approval_steps",
+	"panels",
+	"audit_log",
+	"reports", "report_notify_optout",
+	"punishments", "infractions",
+	"component_state",
+}
+
+// runMigrate creates the schema on -db. With -to-driver it also copies every
+// table into that database, e.g. when moving from sqlite to postgres. Tables
+// that already hold rows in the target are reported and left alone.
+func runMigrate(args []string) error {
+	fs := newFlagSet("migrate")
+	var o options
+	o.register(fs)
+	toDriver := fs.String("to-driver", "", "copy rows into a database using this driver")
+	toDSN := fs.String("to-db", "", "sqlite path or postgres connection string of the copy target")
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	if *toDriver == "" {
+		return migrateSchema(ctx, &o)
+	}
+
+	src, _, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := openDB(*toDriver, *toDSN)
+	if err != nil {
+		return fmt.Errorf("open target: %w", err)
+	}
+	defer dst.Close()
+	if !o.dryRun {
+		if err := applySchema(src); err != nil {
+			return err
+		}
+		if err := applySchema(dst); err != nil {
+			return err
+		}
+	}
+
+	var changes []change
+	for _, table := range copyTables {
+		table := table
+		n, err := countRows(ctx, src, table)
+		if err != nil {
+			// Only possible in a dry run, which does not create tables.
+			changes = append(changes, change{Action: "skip", Detail: fmt.Sprintf("%s: not in source (%v)", table, err)})
+			continue
+		}
+		if m, err := countRows(ctx, dst, table); err == nil && m > 0 {
+			changes = append(changes, change{
+				Action: "conflict", Detail: fmt.Sprintf("%s: target already has %d rows", table, m),
+				apply: func(context.Context) error {
+					return fmt.Errorf("table %s in the target is not empty", table)
+				},
+			})
+			continue
+		}
+		changes = append(changes, change{
+			Action: "copy", Detail: fmt.Sprintf("%s: %d rows to %s", table, n, *toDriver),
+			apply: func(ctx context.Context) error { return copyTable(ctx, src, dst, table) },
+		})
+	}
+	return o.finish(ctx, "migrate", changes)
+}
+
+func countRows(ctx context.Context, db *sqldb.DB, table string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n)
+	return n, err
+}
+
+// copyTable copies every row of table, keeping IDs, in one transaction.
+func copyTable(ctx context.Context, src, dst *sqldb.DB, table string) error {
+	rows, err := src.QueryContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(cols, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range vals {
+			// SQLite hands TEXT back as []byte, which Postgres would read as bytea.
+			if b, ok := v.([]byte); ok {
+				vals[i] = string(b)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, insert, vals...); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Rows keep their IDs, so move the sequence past them.
+	if dst.Dialect == sqldb.Postgres && slices.Contains(cols, "id") {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), MAX(id)) FROM %[1]s HAVING MAX(id) IS NOT NULL`, table)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func migrateSchema(ctx context.Context, o *options) error {
+	changes := []change{{Action: "schema", Detail: "create missing tables"}}
+	if o.dryRun {
+		return o.finish(ctx, "migrate", changes)
+	}
+	db, err := openDB(o.driver, o.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := applySchema(db); err != nil {
+		return err
+	}
+	return o.finish(ctx, "migrate", changes)
+}
+
+// applySchema creates every table the bot uses.
+func applySchema(db *sqldb.DB) error {
+	if _, err := whitelist.New(db); err != nil {
+		return fmt.Errorf("whitelist schema: %w", err)
+	}
+	if _, err := applications.New(db); err != nil {
+		return fmt.Errorf("applications schema: %w", err)
+	}
+	if _, err := approval.New(db); err != nil {
+		return fmt.Errorf("approval schema: %w", err)
+	}
+	if _, err := panels.New(db); err != nil {
+		return fmt.Errorf("panels schema: %w", err)
+	}
+	if _, err := audit.New(db); err != nil {
+		return fmt.Errorf("audit schema: %w", err)
+	}
+	if _, err := reports.New(db); err != nil {
+		return fmt.Errorf("reports schema: %w", err)
+	}
+	if _, err := moderation.New(db); err != nil {
+		return fmt.Errorf("moderation schema: %w", err)
+	}
+	if _, err := components.New(db); err != nil {
+		return fmt.Errorf("component state schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// runPrune removes rows that no longer belong in the database: players that
+// are not in the server's whitelist.json and/or Discord users who have left
+// the guild. At least one source has to be given.
+func runPrune(args []string) error {
+	fs := newFlagSet("prune")
+	var o options
+	o.register(fs)
+	against := fs.String("whitelist", "", "remove rows whose UUID is not in this whitelist.json")
+	departed := fs.Bool("departed", false, "remove rows whose Discord user left the guild (uses DISCORD_TOKEN and GUILD_ID)")
+	_ = fs.Parse(args)
+
+	if *against == "" && !*departed {
+		return errors.New("nothing to prune against; pass -whitelist and/or -departed")
+	}
+
+	db, st, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	entries, err := st.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	reasons := map[string]string{}
+	if *against != "" {
+		wl, err := loadWhitelistJSON(*against)
+		if err != nil {
+			return err
+		}
+		onServer := map[string]bool{}
+		for _, e := range wl {
+			onServer[whitelist.NormalizeUUID(e.UUID)] = true
+		}
+		for _, e := range entries {
+			if !onServer[whitelist.NormalizeUUID(e.MinecraftUUID)] {
+				reasons[e.DiscordID] = "not in " + *against
+			}
+		}
+	}
+	if *departed {
+		gone, err := departedMembers(entries)
+		if err != nil {
+			return err
+		}
+		for _, id := range gone {
+			reasons[id] = "left the guild"
+		}
+	}
+
+	var changes []change
+	for _, e := range entries {
+		reason, ok := reasons[e.DiscordID]
+		if !ok {
+			continue
+		}
+		id := e.DiscordID
+		changes = append(changes, change{
+			Action: "remove", DiscordID: e.DiscordID, UUID: e.MinecraftUUID, Username: e.Username, Detail: reason,
+			apply: func(ctx context.Context) error { return st.Remove(ctx, id) },
+		})
+	}
+	return o.finish(ctx, "prune", changes)
+}
+
+func departedMembers(entries []whitelist.Entry) ([]string, error) {
+	token, guild := os.Getenv("DISCORD_TOKEN"), os.Getenv("GUILD_ID")
+	if token == "" || guild == "" {
+		return nil, errors.New("-departed needs DISCORD_TOKEN and GUILD_ID")
+	}
+	s, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, err
+	}
+
+	var gone []string
+	for _, e := range entries {
+		_, err := s.GuildMember(guild, e.DiscordID)
+		var rest *discordgo.RESTError
+		switch {
+		case err == nil:
+		case errors.As(err, &rest) && rest.Response != nil && rest.Response.StatusCode == http.StatusNotFound:
+			gone = append(gone, e.DiscordID)
+		default:
+			return nil, fmt.Errorf("lookup member %s: %w", e.DiscordID, err)
+		}
+	}
+	return gone, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/discord/namemc"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+func runResolveUUIDs(args []string) error {
+	fs := newFlagSet("resolve-uuids")
+	var o options
+	o.register(fs)
+	delay := fs.Duration("delay", 250*time.Millisecond, "pause between Mojang API calls")
+	_ = fs.Parse(args)
+
+	db, st, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	entries, err := st.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	resolver := namemc.New()
+	var changes []change
+	for _, e := range entries {
+		e := e
+		norm := whitelist.NormalizeUUID(e.MinecraftUUID)
+		detail := "normalized"
+		if !uuidRe.MatchString(norm) {
+			uuid, err := resolver.UsernameToUUID(e.Username)
+			time.Sleep(*delay)
+			if err != nil {
+				changes = append(changes, change{Action: "skip", DiscordID: e.DiscordID, UUID: e.MinecraftUUID, Username: e.Username, Detail: "resolve failed: " + err.Error()})
+				continue
+			}
+			norm = whitelist.NormalizeUUID(uuid)
+			detail = "resolved from username"
+		}
+		if norm == e.MinecraftUUID {
+			continue
+		}
+		changes = append(changes, change{
+			Action: "update", DiscordID: e.DiscordID, UUID: norm, Username: e.Username,
+			Detail: detail + ", was " + quoteEmpty(e.MinecraftUUID),
+			apply: func(ctx context.Context) error {
+				return st.UpdateUUID(ctx, e.DiscordID, norm)
+			},
+		})
+	}
+	return o.finish(ctx, "resolve-uuids", changes)
+}
+
+func quoteEmpty(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return s
+}
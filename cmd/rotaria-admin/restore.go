@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/backup"
+)
+
+func runRestore(args []string) error {
+	fs := newFlagSet("restore")
+	dbPath := fs.String("db", envOr("DB_PATH", "./database.db"), "path to sqlite database to overwrite")
+	from := fs.String("from", "", "backup file to restore (default: newest in -dir)")
+	dir := fs.String("dir", envOr("BACKUP_DIR", "./backups"), "backup directory")
+	dryRun := fs.Bool("dry-run", false, "verify the backup without replacing the database")
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	src := *from
+	if src == "" {
+		files, err := backup.List(*dir)
+		if err != nil {
+			return fmt.Errorf("list backups: %w", err)
+		}
+		if len(files) == 0 {
+			return errors.New("no backups found in " + *dir)
+		}
+		src = files[0]
+	}
+
+	if *dryRun {
+		if err := backup.Verify(ctx, src); err != nil {
+			return err
+		}
+		fmt.Printf("restore (dry run): %s is valid and would replace %s\n", src, *dbPath)
+		return nil
+	}
+	if err := backup.Restore(ctx, src, *dbPath); err != nil {
+		return err
+	}
+	fmt.Printf("restore: %s -> %s. Start the bot again to use the restored database.\n", src, *dbPath)
+	return nil
+}
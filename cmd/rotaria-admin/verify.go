@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/discord/namemc"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+var (
+	uuidRe      = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	mcNameRe    = regexp.MustCompile(`^[A-Za-z0-9_]{3,16}$`)
+	discordIDRe = regexp.MustCompile(`^[0-9]{17,20}$`)
+)
+
+// runVerify only reads; issues are reported with the "issue" action and make
+// the command exit non-zero.
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	var o options
+	o.register(fs)
+	online := fs.Bool("online", false, "also check each username resolves to the stored UUID")
+	_ = fs.Parse(args)
+
+	db, st, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	entries, err := st.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var resolver *namemc.Client
+	if *online {
+		resolver = namemc.New()
+	}
+	issues := verifyEntries(entries, resolver)
+	if err := o.finish(ctx, "verify", issues); err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d issue(s) found", len(issues))
+	}
+	return nil
+}
+
+func verifyEntries(entries []whitelist.Entry, resolver *namemc.Client) []change {
+	var issues []change
+	issue := func(e whitelist.Entry, detail string) {
+		issues = append(issues, change{Action: "issue", DiscordID: e.DiscordID, UUID: e.MinecraftUUID, Username: e.Username, Detail: detail})
+	}
+
+	seenUUID := map[string]string{}
+	seenName := map[string]string{}
+	for _, e := range entries {
+		norm := whitelist.NormalizeUUID(e.MinecraftUUID)
+		switch {
+		case e.MinecraftUUID == "":
+			issue(e, "missing uuid")
+		case !uuidRe.MatchString(norm):
+			issue(e, "malformed uuid")
+		case norm != e.MinecraftUUID:
+			issue(e, "uuid not normalized (hyphens or upper case)")
+		}
+		if !mcNameRe.MatchString(e.Username) {
+			issue(e, "invalid minecraft username")
+		}
+		if !discordIDRe.MatchString(e.DiscordID) {
+			issue(e, "invalid discord id")
+		}
+		if other, ok := seenUUID[norm]; ok && norm != "" {
+			issue(e, "duplicate uuid, also on discord "+other)
+		}
+		seenUUID[norm] = e.DiscordID
+		lower := strings.ToLower(e.Username)
+		if other, ok := seenName[lower]; ok {
+			issue(e, "duplicate username (case-insensitive), also on discord "+other)
+		}
+		seenName[lower] = e.DiscordID
+
+		if resolver != nil && uuidRe.MatchString(norm) {
+			if uuid, err := resolver.UsernameToUUID(e.Username); err != nil {
+				issue(e, "username does not resolve: "+err.Error())
+			} else if whitelist.NormalizeUUID(uuid) != norm {
+				issue(e, "username now belongs to "+uuid)
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,127 @@
+// Package activity remembers what players recently did on the Minecraft
+// server, as seen through the bridge, so reports can quote it as evidence.
+// It is kept in memory only and starts empty after a restart.
+package activity
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits per player and overall; the least recently seen players are
+// forgotten first.
+const (
+	MaxChatLines = 50
+	MaxSessions  = 10
+	MaxPlayers   = 500
+)
+
+type ChatLine struct {
+	At   time.Time
+	Text string
+}
+
+// Session is one stay on the server. Left is zero while the player is
+// online, and Joined is zero when the bot only saw the player leave.
+type Session struct {
+	Joined time.Time
+	Left   time.Time
+}
+
+type player struct {
+	chat     []ChatLine
+	sessions []Session
+	seen     time.Time
+}
+
+type Log struct {
+	mu      sync.Mutex
+	players map[string]*player
+	now     func() time.Time
+}
+
+func New() *Log {
+	return &Log{players: map[string]*player{}, now: time.Now}
+}
+
+func (l *Log) get(name string) *player {
+	key := strings.ToLower(name)
+	p := l.players[key]
+	if p == nil {
+		if len(l.players) >= MaxPlayers {
+			l.evict()
+		}
+		p = &player{}
+		l.players[key] = p
+	}
+	p.seen = l.now()
+	return p
+}
+
+func (l *Log) evict() {
+	var oldest string
+	var at time.Time
+	for k, p := range l.players {
+		if oldest == "" || p.seen.Before(at) {
+			oldest, at = k, p.seen
+		}
+	}
+	delete(l.players, oldest)
+}
+
+// Chat records a relayed chat line.
+func (l *Log) Chat(name, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.get(name)
+	p.chat = append(p.chat, ChatLine{At: l.now(), Text: text})
+	if len(p.chat) > MaxChatLines {
+		p.chat = p.chat[len(p.chat)-MaxChatLines:]
+	}
+}
+
+// Join starts a session.
+func (l *Log) Join(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.get(name)
+	p.sessions = append(p.sessions, Session{Joined: l.now()})
+	if len(p.sessions) > MaxSessions {
+		p.sessions = p.sessions[len(p.sessions)-MaxSessions:]
+	}
+}
+
+// Leave ends the open session, or records a leave without a join.
+func (l *Log) Leave(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.get(name)
+	if n := len(p.sessions); n > 0 && p.sessions[n-1].Left.IsZero() {
+		p.sessions[n-1].Left = l.now()
+		return
+	}
+	p.sessions = append(p.sessions, Session{Left: l.now()})
+	if len(p.sessions) > MaxSessions {
+		p.sessions = p.sessions[len(p.sessions)-MaxSessions:]
+	}
+}
+
+// Recent returns copies of up to the last chat lines and sessions of a
+// player, oldest first.
+func (l *Log) Recent(name string, chatLines, sessions int) ([]ChatLine, []Session) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.players[strings.ToLower(name)]
+	if p == nil {
+		return nil, nil
+	}
+	return tail(p.chat, chatLines), tail(p.sessions, sessions)
+}
+
+func tail[T any](s []T, n int) []T {
+	if n < len(s) {
+		s = s[len(s)-n:]
+	}
+	return append([]T(nil), s...)
+}
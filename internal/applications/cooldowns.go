@@ -0,0 +1,36 @@
+package applications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SetCooldown blocks the user from applying again until the given time.
+func (s *Store) SetCooldown(ctx context.Context, discordID string, until time.Time, reason string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO application_cooldowns(discord_id, until, reason) VALUES(?,?,?)
+         ON CONFLICT(discord_id) DO UPDATE SET until=excluded.until, reason=excluded.reason`,
+		discordID, until.Unix(), reason,
+	)
+	return err
+}
+
+// Cooldown returns when the user may apply again, or the zero time if they
+// are not on cooldown.
+func (s *Store) Cooldown(ctx context.Context, discordID string) (time.Time, error) {
+	var until int64
+	err := s.db.QueryRowContext(ctx, `SELECT until FROM application_cooldowns WHERE discord_id=?`, discordID).Scan(&until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := time.Unix(until, 0)
+	if time.Now().After(t) {
+		return time.Time{}, nil
+	}
+	return t, nil
+}
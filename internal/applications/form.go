@@ -0,0 +1,218 @@
+package applications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// UsernameQuestion is the ID of the question holding the Minecraft username.
+// Every form must contain it.
+const UsernameQuestion = "mc_username"
+
+// MaxFieldsPerStep is Discord's limit of text inputs in one modal.
+const MaxFieldsPerStep = 5
+
+type Question struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Style       string `json:"style"` // "short" or "paragraph"
+	Placeholder string `json:"placeholder,omitempty"`
+	Required    bool   `json:"required"`
+	MinLength   int    `json:"min_length,omitempty"`
+	MaxLength   int    `json:"max_length,omitempty"`
+	// Pattern is a regular expression the whole answer must match.
+	Pattern     string `json:"pattern,omitempty"`
+	PatternHint string `json:"pattern_hint,omitempty"`
+
+	re *regexp.Regexp
+}
+
+type Form struct {
+	Title     string     `json:"title"`
+	Questions []Question `json:"questions"`
+}
+
+// DefaultForm is used when no form file is configured.
+func DefaultForm() *Form {
+	f := &Form{
+		Title: "Whitelist Application",
+		Questions: []Question{
+			{ID: UsernameQuestion, Label: "Minecraft Username", Style: "short", Required: true, MinLength: 3, MaxLength: 16, Pattern: whitelist.UsernamePattern, PatternHint: "letters, digits and underscores only"},
+			{ID: "age", Label: "Age", Style: "short", Required: true, MaxLength: 3, Pattern: `[0-9]{1,3}`, PatternHint: "a number"},
+			{ID: "plan", Label: "What will you do?", Style: "short", Required: true},
+		},
+	}
+	_ = f.compile(nil)
+	return f
+}
+
+// compile checks the form and prepares its patterns. Every question in
+// required must be present, in addition to UsernameQuestion.
+func (f *Form) compile(required []string) error {
+	if len(f.Questions) == 0 {
+		return errors.New("form has no questions")
+	}
+	seen := map[string]bool{}
+	for n := range f.Questions {
+		q := &f.Questions[n]
+		if q.ID == "" || q.Label == "" {
+			return fmt.Errorf("question %d: id and label are required", n+1)
+		}
+		if seen[q.ID] {
+			return fmt.Errorf("question %q defined twice", q.ID)
+		}
+		seen[q.ID] = true
+		if q.Style != "paragraph" {
+			q.Style = "short"
+		}
+		if q.ID == UsernameQuestion {
+			// The name ends up in server commands, so its pattern is fixed.
+			if q.Pattern != "" && q.Pattern != whitelist.UsernamePattern {
+				return fmt.Errorf("question %q: pattern must be %s or empty", q.ID, whitelist.UsernamePattern)
+			}
+			q.Pattern = whitelist.UsernamePattern
+			if q.PatternHint == "" {
+				q.PatternHint = "3 to 16 letters, digits and underscores"
+			}
+		}
+		if q.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + q.Pattern + `)$`)
+			if err != nil {
+				return fmt.Errorf("question %q: %w", q.ID, err)
+			}
+			q.re = re
+		}
+	}
+	for _, id := range append([]string{UsernameQuestion}, required...) {
+		if !seen[id] {
+			return fmt.Errorf("form must contain a %q question", id)
+		}
+	}
+	if f.Title == "" {
+		f.Title = "Whitelist Application"
+	}
+	return nil
+}
+
+// Has reports whether the form asks the question with the given ID.
+func (f *Form) Has(id string) bool {
+	for _, q := range f.Questions {
+		if q.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Steps splits the questions into modal sized chunks.
+func (f *Form) Steps() [][]Question {
+	var steps [][]Question
+	for start := 0; start < len(f.Questions); start += MaxFieldsPerStep {
+		end := min(start+MaxFieldsPerStep, len(f.Questions))
+		steps = append(steps, f.Questions[start:end])
+	}
+	return steps
+}
+
+// Validate checks one answer against the question's rules and returns a
+// user facing problem, or "" if the answer is fine.
+func (q Question) Validate(v string) string {
+	if v == "" {
+		if q.Required {
+			return fmt.Sprintf("%s is required.", q.Label)
+		}
+		return ""
+	}
+	n := utf8.RuneCountInString(v)
+	if q.MinLength > 0 && n < q.MinLength {
+		return fmt.Sprintf("%s must be at least %d characters.", q.Label, q.MinLength)
+	}
+	if q.MaxLength > 0 && n > q.MaxLength {
+		return fmt.Sprintf("%s must be at most %d characters.", q.Label, q.MaxLength)
+	}
+	if q.re != nil && !q.re.MatchString(v) {
+		if q.PatternHint != "" {
+			return fmt.Sprintf("%s must be %s.", q.Label, q.PatternHint)
+		}
+		return fmt.Sprintf("%s is not in the expected format.", q.Label)
+	}
+	return ""
+}
+
+// FormSource serves the form defined in a JSON file and reloads it when the
+// file changes, so staff can edit questions without a redeploy. A broken
+// file keeps the last good form, as does one missing a required question.
+type FormSource struct {
+	path     string
+	required []string
+
+	mu      sync.Mutex
+	form    *Form
+	modTime time.Time
+}
+
+// NewFormSource serves the form at path. required lists question IDs other
+// features read answers from, e.g. the age used by screening.
+func NewFormSource(path string, required ...string) *FormSource {
+	s := &FormSource{path: path, required: required, form: DefaultForm()}
+	for _, id := range required {
+		if !s.form.Has(id) {
+			logging.L().Warn("default application form lacks a required question", "question", id)
+		}
+	}
+	return s
+}
+
+func (s *FormSource) Current() *Form {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		return s.form
+	}
+	st, err := os.Stat(s.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.L().Warn("application form stat failed", "path", s.path, "error", err)
+		}
+		return s.form
+	}
+	if st.ModTime().Equal(s.modTime) {
+		return s.form
+	}
+	f, err := LoadForm(s.path, s.required...)
+	if err != nil {
+		logging.L().Error("application form reload failed; keeping previous form", "path", s.path, "error", err)
+		s.modTime = st.ModTime()
+		return s.form
+	}
+	logging.L().Info("application form loaded", "path", s.path, "questions", len(f.Questions))
+	s.form, s.modTime = f, st.ModTime()
+	return s.form
+}
+
+func LoadForm(path string, required ...string) (*Form, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Form
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	f.Title = strings.TrimSpace(f.Title)
+	if err := f.compile(required); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
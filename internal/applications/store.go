@@ -0,0 +1,221 @@
+// Package applications stores whitelist applications and the form that
+// collects them.
+package applications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExpired  = "expired"
+)
+
+// DecidedByPolicy marks applications rejected by automatic screening rather
+// than a staff member.
+const DecidedByPolicy = "policy"
+
+// DecidedByExpiry marks applications closed because nobody decided in time.
+const DecidedByExpiry = "expiry"
+
+// Answer is one question/response pair, kept with the label shown at the time.
+type Answer struct {
+	QuestionID string `json:"id"`
+	Label      string `json:"label"`
+	Value      string `json:"value"`
+}
+
+type Application struct {
+	ID            int64
+	DiscordID     string
+	MinecraftName string
+	MinecraftUUID string
+	Answers       []Answer
+	Status        string
+	ChannelID     string
+	MessageID     string
+	CreatedAt     time.Time
+	DecidedAt     time.Time
+	DecidedBy     string
+	// DiscussionThreadID is the private staff thread about the request.
+	DiscussionThreadID string
+	// InterviewThreadID is the private thread that includes the applicant.
+	InterviewThreadID string
+	Reason            string
+	StaffNote         string
+	// RemindedAt is when staff were last pinged about this pending application.
+	RemindedAt time.Time
+}
+
+// Answer returns the value for a question ID, or "".
+func (a *Application) Answer(id string) string {
+	for _, ans := range a.Answers {
+		if ans.QuestionID == id {
+			return ans.Value
+		}
+	}
+	return ""
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS applications (
+        id {{pk}},
+        discord_id TEXT NOT NULL,
+        minecraft_name TEXT NOT NULL,
+        minecraft_uuid TEXT NOT NULL,
+        answers TEXT NOT NULL,
+        status TEXT NOT NULL,
+        channel_id TEXT NOT NULL DEFAULT '',
+        message_id TEXT NOT NULL DEFAULT '',
+        created_at BIGINT NOT NULL,
+        decided_at BIGINT NOT NULL DEFAULT 0,
+        decided_by TEXT NOT NULL DEFAULT ''
+    )`,
+		`CREATE INDEX IF NOT EXISTS applications_discord_id ON applications(discord_id)`,
+		`CREATE INDEX IF NOT EXISTS applications_message_id ON applications(message_id)`,
+		`CREATE TABLE IF NOT EXISTS application_votes (
+        application_id BIGINT NOT NULL,
+        voter_id TEXT NOT NULL,
+        vote TEXT NOT NULL,
+        created_at BIGINT NOT NULL,
+        PRIMARY KEY (application_id, voter_id)
+    )`,
+		`CREATE TABLE IF NOT EXISTS application_cooldowns (
+        discord_id TEXT PRIMARY KEY,
+        until BIGINT NOT NULL,
+        reason TEXT NOT NULL
+    )`,
+		`CREATE TABLE IF NOT EXISTS application_transcripts (
+        id {{pk}},
+        application_id BIGINT NOT NULL,
+        thread_id TEXT NOT NULL,
+        kind TEXT NOT NULL,
+        content TEXT NOT NULL,
+        created_at BIGINT NOT NULL
+    )`,
+	); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	for _, col := range []string{"discussion_thread_id", "interview_thread_id", "reason", "staff_note"} {
+		if err := db.AddColumn(ctx, "applications", col, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.AddColumn(ctx, "applications", "reminded_at", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+const selectColumns = `SELECT id, discord_id, minecraft_name, minecraft_uuid, answers, status, channel_id, message_id, created_at, decided_at, decided_by, discussion_thread_id, interview_thread_id, reason, staff_note, reminded_at FROM applications`
+
+// Create inserts a pending application and sets its ID.
+func (s *Store) Create(ctx context.Context, app *Application) error {
+	answers, err := json.Marshal(app.Answers)
+	if err != nil {
+		return err
+	}
+	if app.CreatedAt.IsZero() {
+		app.CreatedAt = time.Now()
+	}
+	app.Status = StatusPending
+	return s.db.QueryRowContext(ctx,
+		`INSERT INTO applications(discord_id, minecraft_name, minecraft_uuid, answers, status, created_at) VALUES(?,?,?,?,?,?) RETURNING id`,
+		app.DiscordID, app.MinecraftName, app.MinecraftUUID, string(answers), app.Status, app.CreatedAt.Unix(),
+	).Scan(&app.ID)
+}
+
+func (s *Store) SetMessage(ctx context.Context, id int64, channelID, messageID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE applications SET channel_id=?, message_id=? WHERE id=?`, channelID, messageID, id)
+	return err
+}
+
+// SetStatus records the outcome of an application by ID.
+func (s *Store) SetStatus(ctx context.Context, id int64, status, decidedBy string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE applications SET status=?, decided_by=?, decided_at=? WHERE id=?`,
+		status, decidedBy, time.Now().Unix(), id,
+	)
+	return err
+}
+
+// SetReason stores why an application was rejected. reason is shown to the
+// applicant, note is for staff only.
+func (s *Store) SetReason(ctx context.Context, id int64, reason, note string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE applications SET reason=?, staff_note=? WHERE id=?`, reason, note, id)
+	return err
+}
+
+func (s *Store) SetReminded(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE applications SET reminded_at=? WHERE id=?`, at.Unix(), id)
+	return err
+}
+
+// ListPending returns undecided applications that were posted for review,
+// oldest first.
+func (s *Store) ListPending(ctx context.Context) ([]*Application, error) {
+	rows, err := s.db.QueryContext(ctx, selectColumns+` WHERE status=? AND message_id<>'' ORDER BY id`, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Application
+	for rows.Next() {
+		app, err := scanApplication(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, app)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) Get(ctx context.Context, id int64) (*Application, error) {
+	return scanApplication(s.db.QueryRowContext(ctx, selectColumns+` WHERE id=?`, id))
+}
+
+func (s *Store) GetByMessage(ctx context.Context, messageID string) (*Application, error) {
+	return scanApplication(s.db.QueryRowContext(ctx, selectColumns+` WHERE message_id=?`, messageID))
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApplication(row scanner) (*Application, error) {
+	var (
+		a                                Application
+		answers                          string
+		createdAt, decidedAt, remindedAt int64
+	)
+	if err := row.Scan(&a.ID, &a.DiscordID, &a.MinecraftName, &a.MinecraftUUID, &answers, &a.Status, &a.ChannelID, &a.MessageID, &createdAt, &decidedAt, &a.DecidedBy, &a.DiscussionThreadID, &a.InterviewThreadID, &a.Reason, &a.StaffNote, &remindedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(answers), &a.Answers); err != nil {
+		return nil, err
+	}
+	a.CreatedAt = time.Unix(createdAt, 0)
+	if decidedAt > 0 {
+		a.DecidedAt = time.Unix(decidedAt, 0)
+	}
+	if remindedAt > 0 {
+		a.RemindedAt = time.Unix(remindedAt, 0)
+	}
+	return &a, nil
+}
@@ -0,0 +1,65 @@
+package applications
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ThreadDiscussion = "discussion"
+	ThreadInterview  = "interview"
+)
+
+type Transcript struct {
+	ID            int64
+	ApplicationID int64
+	ThreadID      string
+	Kind          string
+	Content       string
+	CreatedAt     time.Time
+}
+
+func (s *Store) SetDiscussionThread(ctx context.Context, id int64, threadID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE applications SET discussion_thread_id=? WHERE id=?`, threadID, id)
+	return err
+}
+
+func (s *Store) SetInterviewThread(ctx context.Context, id int64, threadID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE applications SET interview_thread_id=? WHERE id=?`, threadID, id)
+	return err
+}
+
+// SaveTranscript stores the text of a thread once the application is decided.
+func (s *Store) SaveTranscript(ctx context.Context, t Transcript) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO application_transcripts(application_id, thread_id, kind, content, created_at) VALUES(?,?,?,?,?)`,
+		t.ApplicationID, t.ThreadID, t.Kind, t.Content, t.CreatedAt.Unix(),
+	)
+	return err
+}
+
+func (s *Store) Transcripts(ctx context.Context, applicationID int64) ([]Transcript, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, application_id, thread_id, kind, content, created_at FROM application_transcripts WHERE application_id=? ORDER BY id`,
+		applicationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Transcript
+	for rows.Next() {
+		var t Transcript
+		var at int64
+		if err := rows.Scan(&t.ID, &t.ApplicationID, &t.ThreadID, &t.Kind, &t.Content, &at); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = time.Unix(at, 0)
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,73 @@
+package applications
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	VoteApprove = "approve"
+	VoteReject  = "reject"
+	VoteVeto    = "veto"
+)
+
+type Vote struct {
+	VoterID   string
+	Vote      string
+	CreatedAt time.Time
+}
+
+// Tally counts the votes on one application.
+type Tally struct {
+	Approvers []string
+	Rejecters []string
+	Vetoers   []string
+}
+
+// CastVote records a staff vote. Voting again replaces the earlier vote.
+func (s *Store) CastVote(ctx context.Context, applicationID int64, voterID, vote string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO application_votes(application_id, voter_id, vote, created_at) VALUES(?,?,?,?)
+         ON CONFLICT(application_id, voter_id) DO UPDATE SET vote=excluded.vote, created_at=excluded.created_at`,
+		applicationID, voterID, vote, time.Now().Unix(),
+	)
+	return err
+}
+
+func (s *Store) Votes(ctx context.Context, applicationID int64) ([]Vote, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT voter_id, vote, created_at FROM application_votes WHERE application_id=? ORDER BY created_at`,
+		applicationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Vote
+	for rows.Next() {
+		var v Vote
+		var at int64
+		if err := rows.Scan(&v.VoterID, &v.Vote, &at); err != nil {
+			return nil, err
+		}
+		v.CreatedAt = time.Unix(at, 0)
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func TallyVotes(votes []Vote) Tally {
+	var t Tally
+	for _, v := range votes {
+		switch v.Vote {
+		case VoteApprove:
+			t.Approvers = append(t.Approvers, v.VoterID)
+		case VoteReject:
+			t.Rejecters = append(t.Rejecters, v.VoterID)
+		case VoteVeto:
+			t.Vetoers = append(t.Vetoers, v.VoterID)
+		}
+	}
+	return t
+}
@@ -0,0 +1,153 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// Action implements one step. Do must be safe to repeat. Undo may be nil
+// when the step has nothing to roll back. Optional steps never fail the
+// saga; they are only reported.
+type Action struct {
+	Do       func(ctx context.Context) error
+	Undo     func(ctx context.Context) error
+	Optional bool
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. A permanent failure of a
+// required step rolls the saga back.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Runner executes sagas. Notify, if set, is called after every state change
+// so the caller can render progress.
+type Runner struct {
+	Store    *Store
+	Attempts int
+	Backoff  time.Duration
+	Notify   func(*Saga)
+}
+
+// Run executes every step that is not done yet, in order. It returns the
+// error of the step that stopped the saga; sg.Status tells whether the saga
+// is left failed (retryable) or was compensated.
+func (r *Runner) Run(ctx context.Context, sg *Saga, actions map[string]Action) error {
+	r.setStatus(ctx, sg, StatusRunning)
+
+	for _, st := range sg.Steps {
+		if st.Status == StepDone {
+			continue
+		}
+		act, ok := actions[st.Name]
+		if !ok {
+			return fmt.Errorf("approval: no action for step %q", st.Name)
+		}
+
+		err := r.attempt(ctx, sg, st, act)
+		if err == nil {
+			continue
+		}
+		if act.Optional {
+			logging.L().Warn("approval: optional step failed", "saga", sg.ID, "step", st.Name, "error", err)
+			continue
+		}
+		if IsPermanent(err) {
+			logging.L().Error("approval: step failed permanently, compensating", "saga", sg.ID, "step", st.Name, "error", err)
+			r.Compensate(ctx, sg, actions)
+			return err
+		}
+		logging.L().Error("approval: step failed", "saga", sg.ID, "step", st.Name, "attempts", st.Attempts, "error", err)
+		r.setStatus(ctx, sg, StatusFailed)
+		return err
+	}
+
+	r.setStatus(ctx, sg, StatusCompleted)
+	return nil
+}
+
+// attempt runs one step with retries and persists its outcome.
+func (r *Runner) attempt(ctx context.Context, sg *Saga, st *StepState, act Action) error {
+	var err error
+	for n := 0; n < max(r.Attempts, 1); n++ {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.Backoff * time.Duration(n)):
+			}
+		}
+		st.Attempts++
+		if err = act.Do(ctx); err == nil || IsPermanent(err) {
+			break
+		}
+	}
+	if err == nil {
+		st.Status, st.Error = StepDone, ""
+	} else {
+		st.Status, st.Error = StepFailed, err.Error()
+	}
+	r.saveStep(ctx, sg, st)
+	return err
+}
+
+// Compensate undoes the completed steps in reverse order and marks the saga
+// compensated. Steps whose undo fails stay done with the error recorded.
+func (r *Runner) Compensate(ctx context.Context, sg *Saga, actions map[string]Action) {
+	for n := len(sg.Steps) - 1; n >= 0; n-- {
+		st := sg.Steps[n]
+		if st.Status != StepDone {
+			continue
+		}
+		act := actions[st.Name]
+		if act.Undo != nil {
+			if err := act.Undo(ctx); err != nil {
+				logging.L().Error("approval: compensation failed", "saga", sg.ID, "step", st.Name, "error", err)
+				st.Error = "undo: " + err.Error()
+				r.saveStep(ctx, sg, st)
+				continue
+			}
+		}
+		st.Status = StepCompensated
+		r.saveStep(ctx, sg, st)
+	}
+	r.setStatus(ctx, sg, StatusCompensated)
+}
+
+func (r *Runner) saveStep(ctx context.Context, sg *Saga, st *StepState) {
+	if err := r.Store.SaveStep(ctx, sg.ID, st); err != nil {
+		logging.L().Error("approval: saving step failed", "saga", sg.ID, "step", st.Name, "error", err)
+	}
+	r.notify(sg)
+}
+
+func (r *Runner) setStatus(ctx context.Context, sg *Saga, status string) {
+	sg.Status = status
+	if err := r.Store.SetStatus(ctx, sg.ID, status); err != nil {
+		logging.L().Error("approval: saving status failed", "saga", sg.ID, "status", status, "error", err)
+	}
+	r.notify(sg)
+}
+
+func (r *Runner) notify(sg *Saga) {
+	if r.Notify != nil {
+		r.Notify(sg)
+	}
+}
@@ -0,0 +1,205 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+func TestMain(m *testing.M) {
+	// Keep the runner's logs out of the source tree.
+	dir, err := os.MkdirTemp("", "approval-test")
+	if err != nil {
+		panic(err)
+	}
+	logging.Init(logging.Options{Path: filepath.Join(dir, "test.log")})
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestSaga(t *testing.T) (*Runner, *Saga) {
+	t.Helper()
+	db, err := sqldb.Open(string(sqldb.SQLite), filepath.Join(t.TempDir(), "approval.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	st, err := New(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	sg := &Saga{GuildID: "g", ChannelID: "c", MessageID: "m", DiscordID: "1", Username: "Alice", UUID: "u", ModeratorID: "2"}
+	if err := st.Create(context.Background(), sg); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return &Runner{Store: st, Attempts: 3}, sg
+}
+
+// okActions returns an action per step that succeeds and records its calls.
+func okActions(calls *[]string) map[string]Action {
+	acts := map[string]Action{}
+	for _, name := range Steps {
+		name := name
+		acts[name] = Action{
+			Do:   func(context.Context) error { *calls = append(*calls, "do "+name); return nil },
+			Undo: func(context.Context) error { *calls = append(*calls, "undo "+name); return nil },
+		}
+	}
+	return acts
+}
+
+func reload(t *testing.T, r *Runner, sg *Saga) *Saga {
+	t.Helper()
+	got, err := r.Store.Get(context.Background(), sg.ID)
+	if err != nil || got == nil {
+		t.Fatalf("get saga %d: %v", sg.ID, err)
+	}
+	return got
+}
+
+func TestRunRetriesTransientFailures(t *testing.T) {
+	r, sg := newTestSaga(t)
+	var calls []string
+	acts := okActions(&calls)
+	fails := 2
+	acts[StepRole] = Action{Do: func(context.Context) error {
+		if fails > 0 {
+			fails--
+			return errors.New("boom")
+		}
+		return nil
+	}}
+
+	if err := r.Run(context.Background(), sg, acts); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	got := reload(t, r, sg)
+	if got.Status != StatusCompleted {
+		t.Fatalf("status = %q, want %q", got.Status, StatusCompleted)
+	}
+	if st := got.Step(StepRole); st.Status != StepDone || st.Attempts != 3 {
+		t.Fatalf("role step = %+v, want done after 3 attempts", st)
+	}
+}
+
+func TestRunLeavesSagaFailedAfterRetries(t *testing.T) {
+	r, sg := newTestSaga(t)
+	var calls []string
+	acts := okActions(&calls)
+	acts[StepRole] = Action{Do: func(context.Context) error { return errors.New("boom") }}
+
+	if err := r.Run(context.Background(), sg, acts); err == nil {
+		t.Fatal("Run = nil, want error")
+	}
+	got := reload(t, r, sg)
+	if got.Status != StatusFailed {
+		t.Fatalf("status = %q, want %q", got.Status, StatusFailed)
+	}
+	if st := got.Step(StepRole); st.Status != StepFailed || st.Attempts != 3 || st.Error != "boom" {
+		t.Fatalf("role step = %+v", st)
+	}
+	if st := got.Step(StepNickname); st.Status != StepPending {
+		t.Fatalf("nickname step = %+v, want pending", st)
+	}
+	for _, c := range calls {
+		if c == "undo "+StepDatabase {
+			t.Fatal("transient failure must not compensate")
+		}
+	}
+}
+
+func TestRunCompensatesPermanentFailure(t *testing.T) {
+	r, sg := newTestSaga(t)
+	var calls []string
+	acts := okActions(&calls)
+	acts[StepRole] = Action{Do: func(context.Context) error { return Permanent(errors.New("boom")) }}
+
+	err := r.Run(context.Background(), sg, acts)
+	if !IsPermanent(err) {
+		t.Fatalf("Run = %v, want permanent error", err)
+	}
+	want := []string{"do database", "do server", "undo server", "undo database"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for n := range want {
+		if calls[n] != want[n] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+	got := reload(t, r, sg)
+	if got.Status != StatusCompensated {
+		t.Fatalf("status = %q, want %q", got.Status, StatusCompensated)
+	}
+	if st := got.Step(StepRole); st.Attempts != 1 {
+		t.Fatalf("permanent failure retried: %+v", st)
+	}
+	for _, name := range []string{StepDatabase, StepServer} {
+		if st := got.Step(name); st.Status != StepCompensated {
+			t.Fatalf("%s step = %+v, want compensated", name, st)
+		}
+	}
+}
+
+func TestRunSkipsFailedOptionalStep(t *testing.T) {
+	r, sg := newTestSaga(t)
+	var calls []string
+	acts := okActions(&calls)
+	acts[StepNickname] = Action{Do: func(context.Context) error { return errors.New("boom") }, Optional: true}
+
+	if err := r.Run(context.Background(), sg, acts); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	got := reload(t, r, sg)
+	if got.Status != StatusCompleted {
+		t.Fatalf("status = %q, want %q", got.Status, StatusCompleted)
+	}
+	if !got.Failed() {
+		t.Fatal("Failed() = false, want the optional failure reported")
+	}
+	if st := got.Step(StepNickname); st.Status != StepFailed {
+		t.Fatalf("nickname step = %+v, want failed", st)
+	}
+}
+
+func TestStorePersistsCreatedEntry(t *testing.T) {
+	r, sg := newTestSaga(t)
+	if reload(t, r, sg).CreatedEntry {
+		t.Fatal("CreatedEntry = true for a new saga, want false")
+	}
+	if err := r.Store.SetCreatedEntry(context.Background(), sg.ID); err != nil {
+		t.Fatalf("SetCreatedEntry: %v", err)
+	}
+	if !reload(t, r, sg).CreatedEntry {
+		t.Error("CreatedEntry = false after SetCreatedEntry, want true")
+	}
+}
+
+func TestTransferSagaRecordsReleasedLinks(t *testing.T) {
+	r, _ := newTestSaga(t)
+	ctx := context.Background()
+	sg := &Saga{GuildID: "g", ChannelID: "c", MessageID: "m2", DiscordID: "1", Username: "Alice", UUID: "u", ModeratorID: "2", Transfer: true}
+	if err := r.Store.Create(ctx, sg); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if sg.Steps[0].Name != StepRelease {
+		t.Fatalf("first step = %q, want %q", sg.Steps[0].Name, StepRelease)
+	}
+	rel := Released{DiscordID: "3", UUID: "old", Username: "Bob", Unwhitelisted: true}
+	if err := r.Store.AddReleased(ctx, sg, rel); err != nil {
+		t.Fatalf("AddReleased: %v", err)
+	}
+	got := reload(t, r, sg)
+	if !got.Transfer || len(got.Released) != 1 || got.Released[0] != rel {
+		t.Errorf("reloaded saga: transfer %v, released %+v; want true, [%+v]", got.Transfer, got.Released, rel)
+	}
+	if len(got.Steps) != len(Steps) {
+		t.Errorf("reloaded %d steps, want %d", len(got.Steps), len(Steps))
+	}
+}
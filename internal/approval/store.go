@@ -0,0 +1,319 @@
+// Package approval runs whitelist approvals as a persisted saga: a fixed
+// list of idempotent steps that are retried, resumed after restarts and
+// undone when one of them fails for good.
+package approval
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+// Saga statuses.
+const (
+	StatusRunning     = "running"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+	StatusCompensated = "compensated"
+)
+
+// Step statuses.
+const (
+	StepPending     = "pending"
+	StepDone        = "done"
+	StepFailed      = "failed"
+	StepCompensated = "compensated"
+)
+
+// Step names, in the order they run. StepRelease only exists on transfers.
+const (
+	StepRelease  = "release"
+	StepDatabase = "database"
+	StepServer   = "server"
+	StepRole     = "role"
+	StepNickname = "nickname"
+)
+
+var Steps = []string{StepRelease, StepDatabase, StepServer, StepRole, StepNickname}
+
+type StepState struct {
+	Name     string
+	Status   string
+	Attempts int
+	Error    string
+}
+
+type Saga struct {
+	ID          int64
+	GuildID     string
+	ChannelID   string
+	MessageID   string
+	DiscordID   string
+	Username    string
+	UUID        string
+	ModeratorID string
+	Status      string
+	Steps       []*StepState
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	// CreatedEntry is set once the saga itself inserted the whitelist row.
+	// Rolling back only deletes rows the saga created.
+	CreatedEntry bool
+	// Transfer adds StepRelease, which removes the links that block the
+	// saga's whitelist row.
+	Transfer bool
+	// Released are the links the saga removed; rolling back restores them.
+	Released []Released
+}
+
+// Released is a whitelist link removed by a transfer.
+type Released struct {
+	DiscordID string `json:"discord_id"`
+	UUID      string `json:"uuid"`
+	Username  string `json:"username"`
+	// Unwhitelisted is set when the player was also removed from the
+	// server whitelist.
+	Unwhitelisted bool `json:"unwhitelisted,omitempty"`
+}
+
+// Step returns the state of the named step.
+func (s *Saga) Step(name string) *StepState {
+	for _, st := range s.Steps {
+		if st.Name == name {
+			return st
+		}
+	}
+	return nil
+}
+
+// Failed reports whether any step ended in failure.
+func (s *Saga) Failed() bool {
+	for _, st := range s.Steps {
+		if st.Status == StepFailed {
+			return true
+		}
+	}
+	return false
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS approval_sagas (
+        id {{pk}},
+        guild_id TEXT NOT NULL,
+        channel_id TEXT NOT NULL,
+        message_id TEXT NOT NULL,
+        discord_id TEXT NOT NULL,
+        username TEXT NOT NULL,
+        uuid TEXT NOT NULL,
+        moderator_id TEXT NOT NULL,
+        status TEXT NOT NULL,
+        created_at BIGINT NOT NULL,
+        updated_at BIGINT NOT NULL
+    )`,
+		`CREATE INDEX IF NOT EXISTS approval_sagas_status ON approval_sagas(status)`,
+		`CREATE TABLE IF NOT EXISTS approval_steps (
+        saga_id BIGINT NOT NULL,
+        step TEXT NOT NULL,
+        status TEXT NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        error TEXT NOT NULL DEFAULT '',
+        updated_at BIGINT NOT NULL,
+        PRIMARY KEY (saga_id, step)
+    )`,
+	); err != nil {
+		return nil, err
+	}
+	for _, c := range []struct{ name, def string }{
+		{"created_entry", "INTEGER NOT NULL DEFAULT 0"},
+		{"transfer", "INTEGER NOT NULL DEFAULT 0"},
+		{"released", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if err := db.AddColumn(context.Background(), "approval_sagas", c.name, c.def); err != nil {
+			return nil, err
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Create inserts a running saga with all steps pending and sets its ID.
+func (s *Store) Create(ctx context.Context, sg *Saga) error {
+	now := time.Now()
+	sg.Status = StatusRunning
+	sg.CreatedAt, sg.UpdatedAt = now, now
+	sg.Steps = nil
+	for _, name := range Steps {
+		if name == StepRelease && !sg.Transfer {
+			continue
+		}
+		sg.Steps = append(sg.Steps, &StepState{Name: name, Status: StepPending})
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO approval_sagas(guild_id, channel_id, message_id, discord_id, username, uuid, moderator_id, created_entry, transfer, status, created_at, updated_at)
+         VALUES(?,?,?,?,?,?,?,?,?,?,?,?) RETURNING id`,
+		sg.GuildID, sg.ChannelID, sg.MessageID, sg.DiscordID, sg.Username, sg.UUID, sg.ModeratorID, boolInt(sg.CreatedEntry), boolInt(sg.Transfer), sg.Status, now.Unix(), now.Unix(),
+	).Scan(&sg.ID); err != nil {
+		return err
+	}
+	for _, st := range sg.Steps {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO approval_steps(saga_id, step, status, attempts, error, updated_at) VALUES(?,?,?,?,?,?)`,
+			sg.ID, st.Name, st.Status, 0, "", now.Unix(),
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) SetStatus(ctx context.Context, id int64, status string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE approval_sagas SET status=?, updated_at=? WHERE id=?`, status, time.Now().Unix(), id)
+	return err
+}
+
+// SetCreatedEntry records that the saga inserted its whitelist row.
+func (s *Store) SetCreatedEntry(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE approval_sagas SET created_entry=1, updated_at=? WHERE id=?`, time.Now().Unix(), id)
+	return err
+}
+
+// AddReleased records a link the saga is about to remove, before it is
+// removed, so a crash in between still rolls it back.
+func (s *Store) AddReleased(ctx context.Context, sg *Saga, r Released) error {
+	released := append(append([]Released(nil), sg.Released...), r)
+	data, err := json.Marshal(released)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE approval_sagas SET released=?, updated_at=? WHERE id=?`, string(data), time.Now().Unix(), sg.ID); err != nil {
+		return err
+	}
+	sg.Released = released
+	return nil
+}
+
+func (s *Store) SaveStep(ctx context.Context, sagaID int64, st *StepState) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE approval_steps SET status=?, attempts=?, error=?, updated_at=? WHERE saga_id=? AND step=?`,
+		st.Status, st.Attempts, st.Error, time.Now().Unix(), sagaID, st.Name,
+	)
+	return err
+}
+
+// Get returns the saga with its steps, or nil if it does not exist.
+func (s *Store) Get(ctx context.Context, id int64) (*Saga, error) {
+	sg, err := scanSaga(s.db.QueryRowContext(ctx, selectColumns+` WHERE id=?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadSteps(ctx, sg); err != nil {
+		return nil, err
+	}
+	return sg, nil
+}
+
+// ListByStatus returns sagas in the given status, oldest first.
+func (s *Store) ListByStatus(ctx context.Context, status string) ([]*Saga, error) {
+	rows, err := s.db.QueryContext(ctx, selectColumns+` WHERE status=? ORDER BY id`, status)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Saga
+	for rows.Next() {
+		sg, err := scanSaga(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, sg)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	for _, sg := range out {
+		if err := s.loadSteps(ctx, sg); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+const selectColumns = `SELECT id, guild_id, channel_id, message_id, discord_id, username, uuid, moderator_id, created_entry, transfer, released, status, created_at, updated_at FROM approval_sagas`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSaga(row scanner) (*Saga, error) {
+	var sg Saga
+	var createdEntry, transfer, created, updated int64
+	var released string
+	if err := row.Scan(&sg.ID, &sg.GuildID, &sg.ChannelID, &sg.MessageID, &sg.DiscordID, &sg.Username, &sg.UUID,
+		&sg.ModeratorID, &createdEntry, &transfer, &released, &sg.Status, &created, &updated); err != nil {
+		return nil, err
+	}
+	sg.CreatedEntry, sg.Transfer = createdEntry != 0, transfer != 0
+	if released != "" {
+		if err := json.Unmarshal([]byte(released), &sg.Released); err != nil {
+			return nil, err
+		}
+	}
+	sg.CreatedAt = time.Unix(created, 0)
+	sg.UpdatedAt = time.Unix(updated, 0)
+	return &sg, nil
+}
+
+// loadSteps fills sg.Steps in run order.
+func (s *Store) loadSteps(ctx context.Context, sg *Saga) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT step, status, attempts, error FROM approval_steps WHERE saga_id=?`, sg.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	byName := map[string]*StepState{}
+	for rows.Next() {
+		var st StepState
+		if err := rows.Scan(&st.Name, &st.Status, &st.Attempts, &st.Error); err != nil {
+			return err
+		}
+		byName[st.Name] = &st
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	sg.Steps = nil
+	for _, name := range Steps {
+		if st, ok := byName[name]; ok {
+			sg.Steps = append(sg.Steps, st)
+		}
+	}
+	return nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
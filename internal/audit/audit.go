@@ -0,0 +1,71 @@
+// Package audit keeps a log of administrative actions taken through the bot.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+type Entry struct {
+	ID        int64
+	ActorID   string
+	Action    string
+	Target    string
+	Detail    string
+	CreatedAt time.Time
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS audit_log (
+        id {{pk}},
+        actor_id TEXT NOT NULL,
+        action TEXT NOT NULL,
+        target TEXT NOT NULL,
+        detail TEXT NOT NULL,
+        created_at BIGINT NOT NULL
+    )`,
+		`CREATE INDEX IF NOT EXISTS audit_log_target ON audit_log(target)`,
+	); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Log records an action. target identifies what was acted on, e.g. a Discord
+// ID or Minecraft name.
+func (s *Store) Log(ctx context.Context, actorID, action, target, detail string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_log(actor_id, action, target, detail, created_at) VALUES(?,?,?,?,?)`,
+		actorID, action, target, detail, time.Now().Unix(),
+	)
+	return err
+}
+
+// ForTarget returns the newest entries about target first.
+func (s *Store) ForTarget(ctx context.Context, target string, limit int) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, actor_id, action, target, detail, created_at FROM audit_log WHERE target=? ORDER BY id DESC LIMIT ?`,
+		target, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Entry
+	for rows.Next() {
+		var e Entry
+		var created int64
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.Target, &e.Detail, &created); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = time.Unix(created, 0)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,167 @@
+// Package backup takes online snapshots of the SQLite database with
+// VACUUM INTO, verifies them and prunes old copies.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+const (
+	filePrefix = "rotaria-"
+	fileSuffix = ".db"
+	// timeLayout keeps nanoseconds so two runs within a second, e.g. the
+	// scheduled one and /backup now, get different files. The fixed width
+	// keeps names sorting by time.
+	timeLayout = "20060102T150405.000000000Z"
+)
+
+type Manager struct {
+	DB  *sqldb.DB
+	Dir string
+	// Keep is how many backups to retain; older ones are deleted after a
+	// successful run. Zero keeps everything.
+	Keep int
+}
+
+type Result struct {
+	Path     string
+	Size     int64
+	Duration time.Duration
+	Pruned   []string
+}
+
+// Run writes a new backup, checks its integrity and applies retention.
+func (m *Manager) Run(ctx context.Context) (Result, error) {
+	if m.DB.Dialect != sqldb.SQLite {
+		return Result{}, errors.New("online backups are only supported for sqlite; use pg_dump for postgres")
+	}
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return Result{}, err
+	}
+
+	start := time.Now()
+	path := filepath.Join(m.Dir, filePrefix+start.UTC().Format(timeLayout)+fileSuffix)
+	if _, err := m.DB.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return Result{}, fmt.Errorf("vacuum into %s: %w", path, err)
+	}
+
+	if err := Verify(ctx, path); err != nil {
+		_ = os.Remove(path)
+		return Result{}, err
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Path: path, Size: st.Size(), Duration: time.Since(start)}
+	res.Pruned, err = m.prune()
+	return res, err
+}
+
+// Verify opens a backup file read-only and runs PRAGMA integrity_check.
+func Verify(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var res string
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&res); err != nil {
+		return fmt.Errorf("integrity check %s: %w", path, err)
+	}
+	if res != "ok" {
+		return fmt.Errorf("integrity check %s: %s", path, res)
+	}
+	return nil
+}
+
+// List returns backup files in dir, newest first.
+func List(dir string) ([]string, error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []string
+	for _, e := range ents {
+		n := e.Name()
+		if !e.IsDir() && strings.HasPrefix(n, filePrefix) && strings.HasSuffix(n, fileSuffix) {
+			out = append(out, filepath.Join(dir, n))
+		}
+	}
+	// Timestamps sort lexically.
+	sort.Sort(sort.Reverse(sort.StringSlice(out)))
+	return out, nil
+}
+
+func (m *Manager) prune() ([]string, error) {
+	if m.Keep <= 0 {
+		return nil, nil
+	}
+	files, err := List(m.Dir)
+	if err != nil || len(files) <= m.Keep {
+		return nil, err
+	}
+	var pruned []string
+	for _, f := range files[m.Keep:] {
+		if err := os.Remove(f); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, f)
+	}
+	return pruned, nil
+}
+
+// Restore replaces the database at dbPath with a verified backup. The bot
+// must be stopped first; stale WAL files are removed so they are not replayed
+// over the restored copy.
+func Restore(ctx context.Context, from, dbPath string) error {
+	if err := Verify(ctx, from); err != nil {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := dbPath + ".restore"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp, dbPath)
+}
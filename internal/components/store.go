@@ -0,0 +1,175 @@
+// Package components keeps the payload of Discord buttons, select menus and
+// modals on the server. A CustomID only carries an action and a short opaque
+// key, so user supplied strings can neither exceed Discord's 100 character
+// limit nor break parsing.
+package components
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+var (
+	ErrNotFound = errors.New("component state not found")
+	ErrExpired  = errors.New("component state expired")
+	// ErrStale means the payload was written by an older version of its kind.
+	ErrStale = errors.New("component state is outdated")
+)
+
+// Kind describes one payload type. Bump Version when the payload changes
+// shape; components holding an older version are then rejected instead of
+// misread. A zero TTL keeps the state until it is deleted.
+type Kind struct {
+	Name    string
+	Version int
+	TTL     time.Duration
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS component_state (
+        state_key TEXT PRIMARY KEY,
+        kind TEXT NOT NULL,
+        version INTEGER NOT NULL,
+        payload TEXT NOT NULL,
+        created_at BIGINT NOT NULL,
+        expires_at BIGINT NOT NULL DEFAULT 0
+    )`,
+		`CREATE INDEX IF NOT EXISTS component_state_expires_at ON component_state(expires_at)`,
+	); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if err := db.AddColumn(ctx, "component_state", "owner", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	if err := db.Migrate(ctx, `CREATE INDEX IF NOT EXISTS component_state_owner ON component_state(kind, owner)`); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Put stores payload as JSON and returns its new key.
+func (s *Store) Put(ctx context.Context, k Kind, payload any) (string, error) {
+	return s.insert(ctx, k, "", payload)
+}
+
+// Keep stores payload for owner, e.g. the report a message shows, and
+// returns the owner's key. Components rendered again for the same owner
+// reuse that key, so re-rendering a message does not add rows.
+func (s *Store) Keep(ctx context.Context, k Kind, owner string, payload any) (string, error) {
+	var key string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT state_key FROM component_state WHERE kind=? AND owner=? ORDER BY created_at DESC LIMIT 1`, k.Name, owner,
+	).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.insert(ctx, k, owner, payload)
+	}
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	var expires int64
+	if k.TTL > 0 {
+		expires = time.Now().Add(k.TTL).Unix()
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE component_state SET version=?, payload=?, expires_at=? WHERE state_key=?`,
+		k.Version, string(data), expires, key)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Drop deletes the state of owner once its components are gone.
+func (s *Store) Drop(ctx context.Context, k Kind, owner string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM component_state WHERE kind=? AND owner=?`, k.Name, owner)
+	return err
+}
+
+func (s *Store) insert(ctx context.Context, k Kind, owner string, payload any) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now()
+	var expires int64
+	if k.TTL > 0 {
+		expires = now.Add(k.TTL).Unix()
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO component_state(state_key, kind, owner, version, payload, created_at, expires_at) VALUES(?,?,?,?,?,?,?)`,
+		key, k.Name, owner, k.Version, string(data), now.Unix(), expires)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Get decodes the payload stored under key into out.
+func (s *Store) Get(ctx context.Context, k Kind, key string, out any) error {
+	var (
+		kind, payload string
+		version       int
+		expires       int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT kind, version, payload, expires_at FROM component_state WHERE state_key=?`, key,
+	).Scan(&kind, &version, &payload, &expires)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case err != nil:
+		return err
+	case kind != k.Name:
+		return ErrNotFound
+	case expires > 0 && time.Now().Unix() >= expires:
+		return ErrExpired
+	case version != k.Version:
+		return ErrStale
+	}
+	return json.Unmarshal([]byte(payload), out)
+}
+
+// Purge deletes state that expired before now.
+func (s *Store) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM component_state WHERE expires_at>0 AND expires_at<=?`, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// sep separates the action from the key. Legacy CustomIDs never contain it.
+const sep = "#"
+
+// ID builds the CustomID of a component: its action and state key.
+func ID(action, key string) string {
+	return action + sep + key
+}
+
+// Split returns the action and key of a CustomID built by ID. ok is false
+// for static IDs and for those posted before component state existed.
+func Split(customID string) (action, key string, ok bool) {
+	return strings.Cut(customID, sep)
+}
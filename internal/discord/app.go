@@ -1,14 +1,28 @@
 package discord
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/activity"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/approval"
+	"github.com/rotaria-smp/rotaria-bot/internal/audit"
+	"github.com/rotaria-smp/rotaria-bot/internal/backup"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
 	"github.com/rotaria-smp/rotaria-bot/internal/discord/blacklist"
 	"github.com/rotaria-smp/rotaria-bot/internal/discord/namemc"
 	"github.com/rotaria-smp/rotaria-bot/internal/mcbridge"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/panels"
+	"github.com/rotaria-smp/rotaria-bot/internal/policy"
+	"github.com/rotaria-smp/rotaria-bot/internal/reconcile"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/config"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
 	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
 )
 
@@ -16,20 +30,147 @@ type App struct {
 	Session          *discordgo.Session
 	Cfg              config.Config
 	Bridge           *mcbridge.Bridge
-	WLStore          *whitelist.Store
+	WLStore          whitelist.Store
 	Blacklist        *blacklist.List
 	NameMC           *namemc.Client
+	Reconciler       *reconcile.Reconciler
+	Backups          *backup.Manager
+	Forms            *applications.FormSource
+	Applications     *applications.Store
+	Approvals        *approval.Store
+	Panels           *panels.Store
+	Audit            *audit.Store
+	Reports          *reports.Store
+	ReportCategories *reports.CategorySource
+	Punishments      *moderation.Store
+	Activity         *activity.Log
+	Components       *components.Store
+	Policy           policy.Rules
+	Weights          moderation.Weights
+	Ladder           moderation.Ladder
+	infractionMu     sync.Mutex
+	drafts           *draftStore
 	lastStatusUpdate time.Time
 }
 
-func NewApp(sess *discordgo.Session, cfg config.Config, bridge *mcbridge.Bridge, wl *whitelist.Store, bl *blacklist.List) *App {
+func NewApp(sess *discordgo.Session, cfg config.Config, bridge *mcbridge.Bridge, db *sqldb.DB, wl whitelist.Store, bl *blacklist.List) (*App, error) {
+	apps, err := applications.New(db)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := approval.New(db)
+	if err != nil {
+		return nil, err
+	}
+	pnl, err := panels.New(db)
+	if err != nil {
+		return nil, err
+	}
+	auditLog, err := audit.New(db)
+	if err != nil {
+		return nil, err
+	}
+	reportStore, err := reports.New(db)
+	if err != nil {
+		return nil, err
+	}
+	punishments, err := moderation.New(db)
+	if err != nil {
+		return nil, err
+	}
+	state, err := components.New(db)
+	if err != nil {
+		return nil, err
+	}
+	nmc := namemc.New()
 	return &App{
-		Session:   sess,
-		Cfg:       cfg,
-		Bridge:    bridge,
-		WLStore:   wl,
-		Blacklist: bl,
-		NameMC:    namemc.New(),
+		Session:          sess,
+		Cfg:              cfg,
+		Bridge:           bridge,
+		WLStore:          wl,
+		Blacklist:        bl,
+		NameMC:           nmc,
+		Reconciler:       &reconcile.Reconciler{Bridge: bridge, Store: wl, Resolver: nmc},
+		Backups:          &backup.Manager{DB: db, Dir: cfg.BackupDir, Keep: cfg.BackupKeep},
+		Forms:            applications.NewFormSource(cfg.ApplicationFormPath, requiredQuestions(cfg)...),
+		Applications:     apps,
+		Approvals:        approvals,
+		Panels:           pnl,
+		Audit:            auditLog,
+		Reports:          reportStore,
+		ReportCategories: reports.NewCategorySource(cfg.ReportCategoriesPath),
+		Punishments:      punishments,
+		Activity:         activity.New(),
+		Components:       state,
+		Policy:           policyRules(cfg),
+		Weights:          infractionWeights(cfg),
+		Ladder:           infractionLadder(cfg),
+		drafts:           newDraftStore(),
+	}, nil
+}
+
+func policyRules(cfg config.Config) policy.Rules {
+	return policy.Rules{
+		MinAge:        cfg.PolicyMinAge,
+		AgeQuestion:   cfg.PolicyAgeQuestion,
+		MinAccountAge: cfg.PolicyMinAccountAge,
+		MinMembership: cfg.PolicyMinMembership,
+		Actions: map[string]policy.Action{
+			policy.RuleMinAge:          policy.ParseAction(cfg.PolicyMinAgeAction, policy.Reject),
+			policy.RuleAccountAge:      policy.ParseAction(cfg.PolicyAccountAgeAction, policy.Flag),
+			policy.RuleMembership:      policy.ParseAction(cfg.PolicyMembershipAction, policy.Flag),
+			policy.RuleUsername:        policy.ParseAction(cfg.PolicyUsernameAction, policy.Reject),
+			policy.RuleWhitelistedUUID: policy.ParseAction(cfg.PolicyWhitelistedUUIDAction, policy.Flag),
+			policy.RuleReapply:         policy.ParseAction(cfg.PolicyReapplyAction, policy.Reject),
+		},
+	}
+}
+
+// requiredQuestions lists the form questions enabled screening rules read.
+func requiredQuestions(cfg config.Config) []string {
+	if cfg.PolicyMinAge > 0 && policy.ParseAction(cfg.PolicyMinAgeAction, policy.Reject) != policy.Off {
+		return []string{cfg.PolicyAgeQuestion}
+	}
+	return nil
+}
+
+// infractionWeights parses INFRACTION_WEIGHTS; invalid settings fall back to
+// one point per infraction.
+func infractionWeights(cfg config.Config) moderation.Weights {
+	w, err := moderation.ParseWeights(cfg.InfractionWeights)
+	if err != nil {
+		logging.L().Warn("ENV: invalid INFRACTION_WEIGHTS, counting one point per infraction", "error", err)
+		return moderation.Weights{}
+	}
+	return w
+}
+
+// infractionLadder parses INFRACTION_LADDER; invalid settings disable
+// automatic punishments rather than guessing.
+func infractionLadder(cfg config.Config) moderation.Ladder {
+	l, err := moderation.ParseLadder(cfg.InfractionLadder)
+	if err != nil {
+		logging.L().Warn("ENV: invalid INFRACTION_LADDER, automatic punishments disabled", "error", err)
+		return nil
+	}
+	return l
+}
+
+// Start launches background jobs. They stop when ctx is cancelled.
+func (a *App) Start(ctx context.Context) {
+	go a.resumeApprovals(ctx)
+	if a.Cfg.ReconcileInterval > 0 {
+		go a.runEvery(ctx, "reconcile", a.Cfg.ReconcileInterval, a.scheduledReconcile)
+	}
+	if a.Cfg.ApplicationCheckInterval > 0 {
+		go a.runEvery(ctx, "stale applications", a.Cfg.ApplicationCheckInterval, a.checkStaleApplications)
+	}
+	if a.Cfg.PunishmentCheckInterval > 0 {
+		go a.runEvery(ctx, "punishments", a.Cfg.PunishmentCheckInterval, a.liftExpiredPunishments)
+	}
+	go a.runEvery(ctx, "component state", time.Hour, a.purgeComponentState)
+	if a.Cfg.BackupInterval > 0 && a.Backups.DB.Dialect == sqldb.SQLite {
+		go a.runEvery(ctx, "backup", a.Cfg.BackupInterval, a.scheduledBackup)
 	}
 }
 
@@ -48,6 +189,20 @@ func (a *App) Register() error {
 		{Name: "report", Description: "Report an issue"},
 		newLookupCommand(lookupPerm),
 		newForceUpdateCommand(adminPerm),
+		newReconcileCommand(adminPerm),
+		newBackupCommand(adminPerm),
+		newSetupCommand(adminPerm),
+		newWLCommand(adminPerm),
+		newReportsCommand(lookupPerm),
+		newReportUpdatesCommand(),
+		newPunishCommand("kick", "Kick a player from the Minecraft server", lookupPerm),
+		newPunishCommand("mute", "Mute a player in Minecraft and on Discord", lookupPerm),
+		newPunishCommand("tempban", "Ban a player for a while", lookupPerm),
+		newPunishCommand("ban", "Ban a player from Minecraft and Discord", lookupPerm),
+		newPunishCommand("unban", "Lift a player's bans", lookupPerm),
+		newInfractionsCommand(lookupPerm),
+		newReportMessageCommand(),
+		newMinecraftAccountCommand(lookupPerm),
 	}
 
 	for _, c := range cmds {
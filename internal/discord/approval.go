@@ -0,0 +1,370 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/approval"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+const (
+	approvalAttempts = 3
+	approvalBackoff  = 2 * time.Second
+)
+
+var approvalStepLabels = map[string]string{
+	approval.StepRelease:  "Release conflicting entries",
+	approval.StepDatabase: "Database entry",
+	approval.StepServer:   "Server whitelist",
+	approval.StepRole:     "Member role",
+	approval.StepNickname: "Nickname",
+}
+
+// requestSaga returns the approval saga of the request message of i.
+func requestSaga(i *discordgo.InteractionCreate, username, uuid, requesterID string) *approval.Saga {
+	return &approval.Saga{
+		GuildID:     i.GuildID,
+		ChannelID:   i.ChannelID,
+		MessageID:   i.Message.ID,
+		DiscordID:   requesterID,
+		Username:    username,
+		UUID:        uuid,
+		ModeratorID: i.Member.User.ID,
+	}
+}
+
+// startApproval saves and runs sg for the request message of i. When the
+// caller already added the whitelist row, the database step only confirms
+// it.
+func (a *App) startApproval(ctx context.Context, i *discordgo.InteractionCreate, sg *approval.Saga) {
+	if err := a.Approvals.Create(ctx, sg); err != nil {
+		logging.L().Error("startApproval: saving saga failed", "username", sg.Username, "error", err)
+		a.followup(i, "Could not start the approval, please try again.", true)
+		return
+	}
+	a.runApproval(ctx, sg, i.Message.Embeds[0])
+}
+
+// runApproval runs the remaining steps, keeping the request embed in sync.
+// The first time the saga completes the application is marked approved and
+// the applicant is welcomed.
+func (a *App) runApproval(ctx context.Context, sg *approval.Saga, base *discordgo.MessageEmbed) {
+	wasCompleted := sg.Status == approval.StatusCompleted
+	r := &approval.Runner{
+		Store:    a.Approvals,
+		Attempts: approvalAttempts,
+		Backoff:  approvalBackoff,
+		Notify:   func(sg *approval.Saga) { a.renderApproval(sg, base) },
+	}
+	err := r.Run(ctx, sg, a.approvalActions(sg))
+	if step := failedStepName(sg); step != "" {
+		title := "Whitelist approval stopped"
+		switch {
+		case sg.Status == approval.StatusCompensated:
+			title = "Whitelist approval rolled back"
+		case err == nil:
+			title = "Whitelist approval completed with warnings"
+			err = errors.New(sg.Step(step).Error)
+		}
+		a.escalate(incident{
+			Title:     title,
+			Step:      approvalStepLabels[step],
+			Err:       err,
+			GuildID:   sg.GuildID,
+			ChannelID: sg.ChannelID,
+			MessageID: sg.MessageID,
+			Subject:   sg.Username,
+			Moderator: sg.ModeratorID,
+		})
+	}
+	if sg.Status != approval.StatusCompleted || wasCompleted {
+		return
+	}
+
+	a.recordDecision(sg.MessageID, sg.ModeratorID, applications.StatusApproved)
+	base = decisionEmbed(base, sg.Username, sg.DiscordID, sg.ModeratorID, true)
+	a.renderApproval(sg, base)
+
+	if dm, err := a.Session.UserChannelCreate(sg.DiscordID); err == nil {
+		_, _ = a.Session.ChannelMessageSend(dm.ID, fmt.Sprintf("✅ You have been whitelisted on Rotaria! Welcome to Rotaria, `%s` 🎉", sg.Username))
+	}
+}
+
+// approvalActions binds the saga's steps to the bridge, the store and Discord.
+// Every Do is idempotent so a retry can rerun it safely.
+func (a *App) approvalActions(sg *approval.Saga) map[string]approval.Action {
+	return map[string]approval.Action{
+		approval.StepRelease: {
+			Do:   func(ctx context.Context) error { return a.releaseConflicts(ctx, sg) },
+			Undo: func(ctx context.Context) error { return a.restoreReleased(ctx, sg) },
+		},
+		approval.StepDatabase: {
+			Do: func(ctx context.Context) error {
+				created, err := a.addWhitelistEntry(ctx, sg.DiscordID, sg.UUID, sg.Username)
+				var conflict *whitelist.ConflictError
+				if errors.As(err, &conflict) {
+					return approval.Permanent(err)
+				}
+				if err != nil || !created || sg.CreatedEntry {
+					return err
+				}
+				// Without the flag a rollback would keep the row, so give it
+				// back and let the retry insert it again.
+				if err := a.Approvals.SetCreatedEntry(ctx, sg.ID); err != nil {
+					_ = a.WLStore.Remove(ctx, sg.DiscordID)
+					return err
+				}
+				sg.CreatedEntry = true
+				return nil
+			},
+			Undo: func(ctx context.Context) error {
+				// A link that existed before the saga is not ours to remove.
+				if !sg.CreatedEntry {
+					return nil
+				}
+				return a.WLStore.Remove(ctx, sg.DiscordID)
+			},
+		},
+		approval.StepServer: {
+			Do: func(ctx context.Context) error {
+				return a.playerCommand(ctx, "whitelist add", sg.Username)
+			},
+			Undo: func(ctx context.Context) error {
+				return a.playerCommand(ctx, "unwhitelist", sg.Username)
+			},
+		},
+		approval.StepRole: {
+			Do: func(ctx context.Context) error {
+				return discordPermanent(a.Session.GuildMemberRoleAdd(sg.GuildID, sg.DiscordID, a.Cfg.MemberRoleID))
+			},
+			Undo: func(ctx context.Context) error {
+				return a.Session.GuildMemberRoleRemove(sg.GuildID, sg.DiscordID, a.Cfg.MemberRoleID)
+			},
+		},
+		approval.StepNickname: {
+			Do: func(ctx context.Context) error {
+				return discordPermanent(a.Session.GuildMemberNickname(sg.GuildID, sg.DiscordID, sg.Username))
+			},
+			Optional: true,
+		},
+	}
+}
+
+// addWhitelistEntry links discordID to the Minecraft account and reports
+// whether it inserted the row; an identical existing row is kept as it is.
+func (a *App) addWhitelistEntry(ctx context.Context, discordID, uuid, username string) (bool, error) {
+	existing, err := a.WLStore.GetByDiscord(ctx, discordID)
+	if err != nil {
+		return false, err
+	}
+	if err := a.WLStore.Add(ctx, discordID, uuid, username); err != nil {
+		return false, err
+	}
+	return existing == nil, nil
+}
+
+func (a *App) bridgeCommand(ctx context.Context, cmd string) error {
+	if !a.Bridge.IsConnected() {
+		return errors.New("minecraft server is not connected")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err := a.Bridge.SendCommand(ctx, cmd)
+	return err
+}
+
+// playerCommand sends a console command that names a player, e.g.
+// "whitelist add Alice". A name that is not a Minecraft username is refused
+// as a permanent error, since retrying cannot make it valid.
+func (a *App) playerCommand(ctx context.Context, cmd, name string) error {
+	if !validPlayerName(name) {
+		return approval.Permanent(fmt.Errorf("invalid player name %q", name))
+	}
+	return a.bridgeCommand(ctx, cmd+" "+name)
+}
+
+// discordPermanent marks client errors such as an unknown member or missing
+// permissions as permanent; retrying them cannot succeed.
+func discordPermanent(err error) error {
+	var rest *discordgo.RESTError
+	if errors.As(err, &rest) && rest.Response != nil &&
+		rest.Response.StatusCode >= 400 && rest.Response.StatusCode < 500 && rest.Response.StatusCode != http.StatusTooManyRequests {
+		return approval.Permanent(err)
+	}
+	return err
+}
+
+// renderApproval shows the saga's progress on the request message.
+func (a *App) renderApproval(sg *approval.Saga, base *discordgo.MessageEmbed) {
+	if sg.MessageID == "" {
+		return
+	}
+	cp := *base
+	cp.Fields = append([]*discordgo.MessageEmbedField(nil), base.Fields...)
+	removeEmbedField(&cp, "Conflict")
+	setEmbedField(&cp, "Approval", approvalStepsText(sg), false)
+
+	components := []discordgo.MessageComponent{}
+	switch sg.Status {
+	case approval.StatusFailed:
+		components = a.approvalComponents(sg.ID, true)
+	case approval.StatusCompleted:
+		if sg.Failed() {
+			components = a.approvalComponents(sg.ID, false)
+		}
+	case approval.StatusCompensated:
+		cp.Color = 0xF59E0B
+		components = a.decisionComponents(sg.Username, sg.DiscordID)
+	}
+
+	embeds := []*discordgo.MessageEmbed{&cp}
+	if _, err := a.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    sg.ChannelID,
+		ID:         sg.MessageID,
+		Embeds:     &embeds,
+		Components: &components,
+	}); err != nil {
+		logging.L().Warn("renderApproval: message edit failed", "saga", sg.ID, "error", err)
+	}
+}
+
+func approvalStepsText(sg *approval.Saga) string {
+	lines := make([]string, 0, len(sg.Steps)+1)
+	for _, st := range sg.Steps {
+		var icon string
+		switch st.Status {
+		case approval.StepDone:
+			icon = "✅"
+		case approval.StepFailed:
+			icon = "❌"
+		case approval.StepCompensated:
+			icon = "↩️"
+		default:
+			icon = ternary(sg.Status == approval.StatusRunning, "⏳", "▫️")
+		}
+		line := icon + " " + approvalStepLabels[st.Name]
+		if st.Error != "" {
+			line += fmt.Sprintf(" — %s (%d attempts)", truncate(st.Error, 120), st.Attempts)
+		}
+		lines = append(lines, line)
+	}
+	if sg.Status == approval.StatusCompensated {
+		lines = append(lines, "Approval was rolled back; the request can be decided again.")
+	}
+	return truncate(strings.Join(lines, "\n"), 1024)
+}
+
+// failedStepName returns the first failed step of sg, or "".
+func failedStepName(sg *approval.Saga) string {
+	for _, st := range sg.Steps {
+		if st.Status == approval.StepFailed {
+			return st.Name
+		}
+	}
+	return ""
+}
+
+func (a *App) approvalComponents(sagaID int64, rollback bool) []discordgo.MessageComponent {
+	key := a.keepComponentState(context.Background(), approvalState, strconv.FormatInt(sagaID, 10), approvalPayload{SagaID: sagaID})
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{CustomID: components.ID("approval_retry", key), Label: "Retry failed steps", Style: discordgo.PrimaryButton},
+	}
+	if rollback {
+		buttons = append(buttons, discordgo.Button{CustomID: components.ID("approval_rollback", key), Label: "Roll back", Style: discordgo.DangerButton})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// handleApprovalButton retries or rolls back a stopped approval.
+func (a *App) handleApprovalButton(i *discordgo.InteractionCreate) {
+	custom := i.MessageComponentData().CustomID
+	if len(i.Message.Embeds) == 0 {
+		a.reply(i, "Missing embed.", true)
+		return
+	}
+	ctx := context.Background()
+	action, rawID, legacy := strings.Cut(custom, "|")
+	var id int64
+	if legacy {
+		// Buttons posted before component state: "approval_retry|<id>".
+		id, _ = strconv.ParseInt(rawID, 10, 64)
+	} else {
+		var st approvalPayload
+		if err := a.componentState(ctx, custom, approvalState, &st); err != nil {
+			a.replyComponentStateError(i, err)
+			return
+		}
+		action, id = componentAction(custom), st.SagaID
+	}
+
+	sg, err := a.Approvals.Get(ctx, id)
+	if err != nil || sg == nil {
+		logging.L().Error("handleApprovalButton: lookup failed", "saga", id, "error", err)
+		a.reply(i, "Could not load this approval.", true)
+		return
+	}
+	if sg.Status == approval.StatusRunning || sg.Status == approval.StatusCompensated {
+		a.reply(i, fmt.Sprintf("This approval is %s.", sg.Status), true)
+		return
+	}
+	if action == "approval_rollback" && sg.Status == approval.StatusCompleted {
+		a.reply(i, "This approval already completed and cannot be rolled back.", true)
+		return
+	}
+
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		logging.L().Error("handleApprovalButton: defer failed", "error", err)
+		return
+	}
+	logging.L().Info("approval action", "saga", id, "action", action, "moderator", i.Member.User.ID)
+
+	base := i.Message.Embeds[0]
+	if action == "approval_rollback" {
+		r := &approval.Runner{Store: a.Approvals, Notify: func(sg *approval.Saga) { a.renderApproval(sg, base) }}
+		r.Compensate(ctx, sg, a.approvalActions(sg))
+		return
+	}
+	a.runApproval(ctx, sg, base)
+}
+
+// resumeApprovals marks sagas interrupted by a restart as failed so staff
+// can retry or roll them back from their message.
+func (a *App) resumeApprovals(ctx context.Context) {
+	sagas, err := a.Approvals.ListByStatus(ctx, approval.StatusRunning)
+	if err != nil {
+		logging.L().Error("resumeApprovals: list failed", "error", err)
+		return
+	}
+	for _, sg := range sagas {
+		if sg.MessageID == "" {
+			// Manual adds without a status message have no buttons to retry
+			// from, so they are resumed directly.
+			logging.L().Warn("resuming manual whitelist add interrupted by restart", "saga", sg.ID, "username", sg.Username)
+			a.runApproval(ctx, sg, manualAddEmbed(sg))
+			continue
+		}
+		logging.L().Warn("approval interrupted by restart", "saga", sg.ID, "username", sg.Username)
+		sg.Status = approval.StatusFailed
+		if err := a.Approvals.SetStatus(ctx, sg.ID, sg.Status); err != nil {
+			logging.L().Error("resumeApprovals: status update failed", "saga", sg.ID, "error", err)
+			continue
+		}
+		msg, err := a.Session.ChannelMessage(sg.ChannelID, sg.MessageID)
+		if err != nil || len(msg.Embeds) == 0 {
+			continue
+		}
+		a.renderApproval(sg, msg.Embeds[0])
+	}
+}
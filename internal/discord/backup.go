@@ -0,0 +1,72 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dustin/go-humanize"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+func newBackupCommand(perm int64) *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:                     "backup",
+		Description:              "Database backups",
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "now", Description: "Take a backup immediately"},
+		},
+	}
+}
+
+func (a *App) handleBackupCommand(i *discordgo.InteractionCreate) {
+	s := a.Session
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := make([]byte, 8192)
+				n := runtime.Stack(stack, false)
+				logging.L().Error("backup panic", "recover", r, "stack", string(stack[:n]))
+				safe := "internal error during backup"
+				_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &safe})
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		var msg string
+		res, err := a.Backups.Run(ctx)
+		if err != nil {
+			logging.L().Error("manual backup failed", "moderator", i.Member.User.ID, "error", err)
+			msg = fmt.Sprintf("Backup failed: %v", err)
+		} else {
+			logging.L().Info("manual backup written", "moderator", i.Member.User.ID, "path", res.Path, "size", res.Size)
+			msg = fmt.Sprintf("✅ Backup `%s` written (%s, %s), integrity ok.", filepath.Base(res.Path), humanize.Bytes(uint64(res.Size)), res.Duration.Round(time.Millisecond))
+			if len(res.Pruned) > 0 {
+				msg += fmt.Sprintf(" Pruned %d old backup(s).", len(res.Pruned))
+			}
+		}
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+	}()
+}
+
+func (a *App) scheduledBackup(ctx context.Context) {
+	res, err := a.Backups.Run(ctx)
+	if err != nil {
+		logging.L().Error("scheduledBackup: failed", "error", err)
+		return
+	}
+	logging.L().Info("scheduledBackup: written", "path", res.Path, "size", res.Size, "duration", res.Duration, "pruned", len(res.Pruned))
+}
@@ -0,0 +1,154 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// Payload kinds of stateful components. Buttons on long-lived staff messages
+// never expire, except drift reports, which go stale within a day, and reuse
+// one key per message through keepComponentState; ephemeral flows expire with
+// the interaction they belong to.
+var (
+	decisionState      = components.Kind{Name: "decision", Version: 1}
+	approvalState      = components.Kind{Name: "approval", Version: 1}
+	reportState        = components.Kind{Name: "report", Version: 1}
+	driftReportState   = components.Kind{Name: "drift_report", Version: 1, TTL: 24 * time.Hour}
+	rejectReasonState  = components.Kind{Name: "reject_reason", Version: 1, TTL: time.Hour}
+	listPageState      = components.Kind{Name: "wl_list", Version: 1, TTL: time.Hour}
+	reportModalState   = components.Kind{Name: "report_modal", Version: 1, TTL: time.Hour}
+	messageReportState = components.Kind{Name: "message_report", Version: 1, TTL: draftTTL}
+	whitelistStepState = components.Kind{Name: "whitelist_step", Version: 1, TTL: draftTTL}
+)
+
+// decisionPayload is the applicant behind a request's decision and conflict
+// buttons.
+type decisionPayload struct {
+	Username    string `json:"username"`
+	RequesterID string `json:"requester_id"`
+}
+
+// approvalPayload is the saga behind a stopped approval's buttons.
+type approvalPayload struct {
+	SagaID int64 `json:"saga_id"`
+}
+
+// listPagePayload is the page shown by a /wl list message.
+type listPagePayload struct {
+	Page   int    `json:"page"`
+	Filter string `json:"filter"`
+}
+
+// stepPayload is the form step a whitelist step button or modal belongs to.
+type stepPayload struct {
+	Step int `json:"step"`
+}
+
+// rejectReasonPayload is the vote being cast and the request it is cast on.
+type rejectReasonPayload struct {
+	Vote      string `json:"vote"`
+	MessageID string `json:"message_id"`
+}
+
+// reportPayload is the report behind its staff buttons and the modals they
+// open. Buttons posted before component state only yield the reporter.
+type reportPayload struct {
+	ReportID   int64  `json:"report_id,omitempty"`
+	ReporterID string `json:"reporter_id"`
+}
+
+// reportModalPayload is the category a report form was opened for.
+type reportModalPayload struct {
+	Category string `json:"category"`
+}
+
+// legacyDecisionPrefixes start the CustomIDs of request buttons posted before
+// component state; they carry "<username>|<requesterID>" inline.
+var legacyDecisionPrefixes = []string{"approve_", "reject_", "veto_", "interview_", "wl_transfer_", "wl_abort_"}
+
+// componentAction returns the action of a stateful CustomID, or the whole ID
+// for static and legacy ones.
+func componentAction(customID string) string {
+	action, _, _ := components.Split(customID)
+	return action
+}
+
+// putComponentState stores payload and returns its key. Errors are logged and
+// yield an empty key, which the handler later reports as an outdated button.
+func (a *App) putComponentState(ctx context.Context, k components.Kind, payload any) string {
+	key, err := a.Components.Put(ctx, k, payload)
+	if err != nil {
+		logging.L().Error("putComponentState: saving failed", "kind", k.Name, "error", err)
+		return ""
+	}
+	return key
+}
+
+// keepComponentState stores payload as the state of owner, reusing the key
+// the owner's components already have. Errors are handled like in
+// putComponentState.
+func (a *App) keepComponentState(ctx context.Context, k components.Kind, owner string, payload any) string {
+	key, err := a.Components.Keep(ctx, k, owner, payload)
+	if err != nil {
+		logging.L().Error("keepComponentState: saving failed", "kind", k.Name, "owner", owner, "error", err)
+		return ""
+	}
+	return key
+}
+
+// componentState decodes the payload behind a stateful CustomID into out.
+func (a *App) componentState(ctx context.Context, customID string, k components.Kind, out any) error {
+	_, key, ok := components.Split(customID)
+	if !ok || key == "" {
+		return components.ErrNotFound
+	}
+	return a.Components.Get(ctx, k, key, out)
+}
+
+// replyComponentStateError explains why a component's state could not be
+// loaded.
+func (a *App) replyComponentStateError(i *discordgo.InteractionCreate, err error) {
+	switch {
+	case errors.Is(err, components.ErrExpired):
+		a.reply(i, "This has expired, please start again.", true)
+	case errors.Is(err, components.ErrNotFound), errors.Is(err, components.ErrStale):
+		a.reply(i, "These buttons are outdated. Please use a newer message or start again.", true)
+	default:
+		logging.L().Error("component state lookup failed", "error", err)
+		a.reply(i, "Something went wrong, please try again.", true)
+	}
+}
+
+// decision resolves the applicant of a decision or conflict button.
+func (a *App) decision(ctx context.Context, customID string) (decisionPayload, error) {
+	var d decisionPayload
+	for _, p := range legacyDecisionPrefixes {
+		if rest, ok := strings.CutPrefix(customID, p); ok {
+			username, requesterID, ok := strings.Cut(rest, "|")
+			if !ok {
+				return d, components.ErrNotFound
+			}
+			return decisionPayload{Username: username, RequesterID: requesterID}, nil
+		}
+	}
+	err := a.componentState(ctx, customID, decisionState, &d)
+	return d, err
+}
+
+// purgeComponentState drops expired component state.
+func (a *App) purgeComponentState(ctx context.Context) {
+	n, err := a.Components.Purge(ctx, time.Now())
+	if err != nil {
+		logging.L().Warn("purgeComponentState: failed", "error", err)
+		return
+	}
+	if n > 0 {
+		logging.L().Debug("purgeComponentState: removed expired state", "rows", n)
+	}
+}
@@ -0,0 +1,170 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+const (
+	reportMessageCommand    = "Report message"
+	minecraftAccountCommand = "Minecraft account"
+)
+
+// messageReportType is the report type of reported Discord messages. A
+// report category with this ID can route them elsewhere.
+const messageReportType = "message"
+
+func newReportMessageCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:     reportMessageCommand,
+		Type:     discordgo.MessageApplicationCommand,
+		Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+	}
+}
+
+func newMinecraftAccountCommand(perm int64) *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:                     minecraftAccountCommand,
+		Type:                     discordgo.UserApplicationCommand,
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+	}
+}
+
+// messageSnapshot is a reported message as it was when the reporter opened
+// the modal, so deleting it afterwards keeps its author and text. Attachments
+// are only kept as CDN links, which stop working once the message is gone.
+// It is kept as the modal's component state.
+type messageSnapshot struct {
+	ReporterID  string         `json:"reporter_id"`
+	GuildID     string         `json:"guild_id"`
+	ChannelID   string         `json:"channel_id"`
+	MessageID   string         `json:"message_id"`
+	AuthorID    string         `json:"author_id,omitempty"`
+	AuthorName  string         `json:"author_name,omitempty"`
+	Content     string         `json:"content,omitempty"`
+	Attachments []snapshotFile `json:"attachments,omitempty"`
+	SentAt      int64          `json:"sent_at"`
+}
+
+type snapshotFile struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// openReportMessageModal snapshots the target message and asks the reporter
+// what is wrong with it.
+func (a *App) openReportMessageModal(i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	msg := data.Resolved.Messages[data.TargetID]
+	if msg == nil {
+		a.reply(i, "Could not read that message.", true)
+		return
+	}
+	reporter := interactionUserID(i)
+	if msg.Author != nil && msg.Author.ID == reporter {
+		a.reply(i, "You cannot report your own message.", true)
+		return
+	}
+	snap := messageSnapshot{
+		ReporterID: reporter,
+		GuildID:    ternary(msg.GuildID != "", msg.GuildID, i.GuildID),
+		ChannelID:  ternary(msg.ChannelID != "", msg.ChannelID, i.ChannelID),
+		MessageID:  msg.ID,
+		Content:    msg.Content,
+		SentAt:     msg.Timestamp.Unix(),
+	}
+	if msg.Author != nil {
+		snap.AuthorID, snap.AuthorName = msg.Author.ID, msg.Author.Username
+	}
+	for _, att := range msg.Attachments {
+		snap.Attachments = append(snap.Attachments, snapshotFile{Name: att.Filename, URL: att.URL})
+	}
+	key := a.putComponentState(context.Background(), messageReportState, snap)
+
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: components.ID("report_msg_modal", key),
+			Title:    "Report Message",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: reports.FieldDetails, Label: "What is wrong with this message?", Style: discordgo.TextInputParagraph, Required: true, MaxLength: 1000},
+				}},
+			},
+		},
+	})
+}
+
+func (a *App) handleReportMessageSubmit(i *discordgo.InteractionCreate) {
+	reporter := interactionUserID(i)
+	var msg messageSnapshot
+	err := a.componentState(context.Background(), i.ModalSubmitData().CustomID, messageReportState, &msg)
+	if err == nil && msg.ReporterID != reporter {
+		err = components.ErrNotFound
+	}
+	if err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	if channelID, _ := a.reportDestination(messageReportType); channelID == "" {
+		logging.L().Warn("handleReportMessageSubmit: no report channel configured; report not delivered", "reporter_discord_id", reporter)
+		a.reply(i, "Report could not be delivered at the moment. Please contact staff members if this issue persists.", true)
+		return
+	}
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		logging.L().Error("handleReportMessageSubmit: defer failed", "error", err)
+		return
+	}
+
+	ctx := context.Background()
+	link := messageLink(msg.GuildID, msg.ChannelID, msg.MessageID)
+	r := &reports.Report{
+		ReporterID: reporter,
+		Type:       messageReportType,
+		Details:    strings.TrimSpace(modalValue(i, reports.FieldDetails)),
+		Context:    fmt.Sprintf("Sent in <#%s> <t:%d:f> — [jump](%s)", msg.ChannelID, msg.SentAt, link),
+	}
+	if msg.AuthorID != "" {
+		author := fmt.Sprintf("<@%s> (`%s`)", msg.AuthorID, msg.AuthorName)
+		// A linked author can be punished from the report like a player.
+		if e, err := a.WLStore.GetByDiscord(ctx, msg.AuthorID); err == nil && e != nil {
+			r.TargetPlayer, r.TargetUUID = e.Username, e.MinecraftUUID
+		}
+		r.Extra = append(r.Extra, reports.Answer{Label: "Author", Value: author})
+	}
+	if content := strings.TrimSpace(msg.Content); content != "" {
+		r.Extra = append(r.Extra, reports.Answer{Label: "Message", Value: truncate(content, 1000)})
+	}
+	var files []string
+	for _, att := range msg.Attachments {
+		files = append(files, fmt.Sprintf("[%s](%s)", att.Name, att.URL))
+	}
+	r.Evidence = truncate(strings.Join(files, "\n"), 1000)
+	a.fileReport(ctx, i, r)
+}
+
+// handleMinecraftAccountCommand shows the whitelist entry of the target user.
+func (a *App) handleMinecraftAccountCommand(i *discordgo.InteractionCreate) {
+	target := i.ApplicationCommandData().TargetID
+	ctx := context.Background()
+	entry, err := a.WLStore.GetByDiscord(ctx, target)
+	switch {
+	case err != nil:
+		logging.L().Error("minecraft account: lookup failed", "discord_id", target, "error", err)
+		a.reply(i, "Lookup failed, please try again later.", true)
+	case entry == nil:
+		a.reply(i, fmt.Sprintf("<@%s> is not linked to a Minecraft account.", target), true)
+	default:
+		a.reply(i, a.wlInfo(ctx, entry), true)
+	}
+}
@@ -0,0 +1,67 @@
+package discord
+
+import (
+	"sync"
+	"time"
+)
+
+const draftTTL = 30 * time.Minute
+
+// draftStore keeps the answers of multi-step applications between modals.
+// Drafts live in memory only; an expired or lost draft means starting over.
+type draftStore struct {
+	mu     sync.Mutex
+	drafts map[string]*draft
+}
+
+type draft struct {
+	answers map[string]string
+	updated time.Time
+}
+
+func newDraftStore() *draftStore {
+	return &draftStore{drafts: map[string]*draft{}}
+}
+
+func (s *draftStore) set(userID, questionID, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	d := s.drafts[userID]
+	if d == nil {
+		d = &draft{answers: map[string]string{}}
+		s.drafts[userID] = d
+	}
+	d.answers[questionID] = value
+	d.updated = time.Now()
+}
+
+func (s *draftStore) value(userID, questionID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d := s.drafts[userID]; d != nil && time.Since(d.updated) < draftTTL {
+		return d.answers[questionID]
+	}
+	return ""
+}
+
+func (s *draftStore) exists(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.drafts[userID]
+	return d != nil && time.Since(d.updated) < draftTTL
+}
+
+func (s *draftStore) clear(userID string) {
+	s.mu.Lock()
+	delete(s.drafts, userID)
+	s.mu.Unlock()
+}
+
+func (s *draftStore) expire() {
+	for id, d := range s.drafts {
+		if time.Since(d.updated) >= draftTTL {
+			delete(s.drafts, id)
+		}
+	}
+}
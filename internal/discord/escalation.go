@@ -0,0 +1,75 @@
+package discord
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// incident describes a failure that staff have to look at.
+type incident struct {
+	Title     string
+	Step      string
+	Err       error
+	GuildID   string
+	ChannelID string
+	MessageID string
+	Subject   string // e.g. the Minecraft name involved
+	Moderator string
+}
+
+// escalate posts inc to the escalation channel, pinging the configured role
+// and on-call users. Without a channel it is only logged.
+func (a *App) escalate(inc incident) {
+	logging.L().Error("incident", "title", inc.Title, "step", inc.Step, "subject", inc.Subject, "error", inc.Err)
+	if a.Cfg.EscalationChannelID == "" {
+		logging.L().Warn("escalate: ESCALATION_CHANNEL_ID not configured; incident not posted", "title", inc.Title)
+		return
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title:     "⚠️ " + inc.Title,
+		Color:     0xEF4444,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if inc.Step != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: "Step", Value: inc.Step, Inline: true})
+	}
+	if inc.Subject != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: "Player", Value: "`" + inc.Subject + "`", Inline: true})
+	}
+	if inc.Moderator != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: "Moderator", Value: "<@" + inc.Moderator + ">", Inline: true})
+	}
+	if inc.Err != nil {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: "Error", Value: "```" + truncate(inc.Err.Error(), 1000) + "```"})
+	}
+	if inc.MessageID != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: "Application", Value: messageLink(inc.GuildID, inc.ChannelID, inc.MessageID)})
+	}
+
+	var content string
+	mentions := &discordgo.MessageAllowedMentions{}
+	if a.Cfg.EscalationRoleID != "" {
+		content += "<@&" + a.Cfg.EscalationRoleID + "> "
+		mentions.Roles = []string{a.Cfg.EscalationRoleID}
+	}
+	for _, id := range a.Cfg.EscalationUserIDs {
+		content += "<@" + id + "> "
+		mentions.Users = append(mentions.Users, id)
+	}
+
+	if _, err := a.Session.ChannelMessageSendComplex(a.Cfg.EscalationChannelID, &discordgo.MessageSend{
+		Content:         content,
+		Embeds:          []*discordgo.MessageEmbed{e},
+		AllowedMentions: mentions,
+	}); err != nil {
+		logging.L().Error("escalate: posting incident failed", "title", inc.Title, "error", err)
+	}
+}
+
+func messageLink(guildID, channelID, messageID string) string {
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, channelID, messageID)
+}
@@ -8,12 +8,14 @@ import (
 	"time"
 
 	"github.com/rotaria-smp/discordwebhook"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
 )
 
 var (
 	chatLineRe = regexp.MustCompile(`^<([^>]+)>[ ]?(.*)$`)
 	joinLineRe = regexp.MustCompile(`^\*\*([A-Za-z0-9_]+)\*\* joined the server\.$`)
+	leftLineRe = regexp.MustCompile(`^\*\*([A-Za-z0-9_]+)\*\* left the server\.$`)
 	atEveryone = regexp.MustCompile(`@everyone`)
 	nameRe     = regexp.MustCompile(`([A-Za-z0-9_]+)$`)
 )
@@ -51,6 +53,7 @@ func (a *App) HandleMCEvent(topic, body string) {
 		if m := joinLineRe.FindStringSubmatch(body); m != nil {
 			mcName := m[1] // e.g. "limp4n__"
 			logging.L().Debug("Parsed join username", "minecraft_name", mcName)
+			a.Activity.Join(mcName)
 
 			// sync in background so we don't block event handling
 			go a.handlePlayerJoinSync(mcName)
@@ -60,6 +63,17 @@ func (a *App) HandleMCEvent(topic, body string) {
 		return
 	}
 
+	if topic == "report" {
+		go a.handleInGameReport(body)
+		return
+	}
+
+	if topic == "leave" {
+		if m := leftLineRe.FindStringSubmatch(body); m != nil {
+			a.Activity.Leave(m[1])
+		}
+	}
+
 	if topic == "leave" || topic == "lifecycle" {
 		a.sendWebhook("Rotaria", body, rotariaAvatarUrl)
 		return
@@ -69,6 +83,8 @@ func (a *App) HandleMCEvent(topic, body string) {
 		msg := body
 		fullUsername := "server"
 		minecraftName := "server"
+		// player is only set for lines a player sent, not server broadcasts.
+		player := ""
 
 		if m := chatLineRe.FindStringSubmatch(body); m != nil {
 			// m[1] is e.g. "[Owner] Awiant"
@@ -82,6 +98,8 @@ func (a *App) HandleMCEvent(topic, body string) {
 			}
 
 			msg = m[2]
+			player = minecraftName
+			a.Activity.Chat(minecraftName, msg)
 		}
 
 		// Defang @everyone mentions to a clearly broken form (no leading '@')
@@ -89,9 +107,13 @@ func (a *App) HandleMCEvent(topic, body string) {
 
 		if a.Blacklist != nil && a.Blacklist.Contains(msg) {
 			logging.L().Info("Blocked message from user (blacklist hit)", "message", msg, "user", minecraftName)
+			if !validPlayerName(player) {
+				return
+			}
+			go a.recordInfraction(&moderation.Infraction{Player: player, Kind: moderation.BlacklistMinecraft, Detail: truncate(msg, 200)})
 			if a.Bridge.IsConnected() {
 				ctx := context.Background()
-				if _, err := a.Bridge.SendCommand(ctx, fmt.Sprintf("kick %s", minecraftName)); err != nil {
+				if _, err := a.Bridge.SendCommand(ctx, fmt.Sprintf("kick %s", player)); err != nil {
 					logging.L().Error("kick failed after blacklist hit", "minecraft_name", minecraftName, "error", err)
 				}
 			}
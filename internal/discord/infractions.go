@@ -0,0 +1,178 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+const infractionsShown = 10
+
+// infractionsSince is the start of the window in which points still count.
+func (a *App) infractionsSince() time.Time {
+	if a.Cfg.InfractionDecay <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-a.Cfg.InfractionDecay)
+}
+
+// recordInfraction stores an offence against the linked identities and
+// applies the ladder step its points cross.
+func (a *App) recordInfraction(inf *moderation.Infraction) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if inf.DiscordID == "" && inf.Player != "" {
+		if e, err := a.WLStore.GetByUsername(ctx, inf.Player); err == nil && e != nil {
+			inf.DiscordID, inf.PlayerUUID = e.DiscordID, e.MinecraftUUID
+		}
+	} else if inf.Player == "" && inf.DiscordID != "" {
+		if e, err := a.WLStore.GetByDiscord(ctx, inf.DiscordID); err == nil && e != nil {
+			inf.Player, inf.PlayerUUID = e.Username, e.MinecraftUUID
+		}
+	}
+	inf.Points = a.Weights.Of(inf.Kind)
+
+	// Serialize so two quick hits cannot both cross the same step.
+	a.infractionMu.Lock()
+	defer a.infractionMu.Unlock()
+
+	before, err := a.Punishments.Points(ctx, inf.DiscordID, inf.Player, a.infractionsSince())
+	if err != nil {
+		logging.L().Error("recordInfraction: points lookup failed", "discord_id", inf.DiscordID, "player", inf.Player, "error", err)
+		return
+	}
+	if err := a.Punishments.AddInfraction(ctx, inf); err != nil {
+		logging.L().Error("recordInfraction: saving failed", "discord_id", inf.DiscordID, "player", inf.Player, "error", err)
+		return
+	}
+	after := before + inf.Points
+	logging.L().Info("infraction recorded", "kind", inf.Kind, "discord_id", inf.DiscordID, "player", inf.Player, "points", after)
+
+	step := a.Ladder.Crossed(before, after)
+	if step == nil || (step.Kind == moderation.Kick && inf.Player == "") {
+		return
+	}
+	p := &moderation.Punishment{
+		Kind:        step.Kind,
+		Player:      inf.Player,
+		PlayerUUID:  inf.PlayerUUID,
+		DiscordID:   inf.DiscordID,
+		Reason:      fmt.Sprintf("Automatic: %d infraction points, latest %s", after, strings.ReplaceAll(inf.Kind, "_", " ")),
+		ModeratorID: moderation.AutoModerator,
+		CreatedAt:   time.Now(),
+	}
+	if step.Duration > 0 {
+		p.ExpiresAt = p.CreatedAt.Add(step.Duration)
+	}
+	if _, err := a.punish(ctx, p); err != nil {
+		logging.L().Error("recordInfraction: automatic punishment failed", "kind", step.Kind, "player", inf.Player, "error", err)
+	}
+}
+
+func newInfractionsCommand(perm int64) *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:                     "infractions",
+		Description:              "Show a player's infractions and punishments",
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Discord user", Required: false},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "player", Description: "Minecraft username", Required: false, MaxLength: 16},
+		},
+	}
+}
+
+func (a *App) handleInfractionsCommand(i *discordgo.InteractionCreate) {
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range i.ApplicationCommandData().Options {
+		opts[o.Name] = o
+	}
+	if opts["user"] == nil && opts["player"] == nil {
+		a.reply(i, "Provide one option: user or player", true)
+		return
+	}
+	// Resolving an unlinked player's UUID can outlive the 3s window.
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	target, msg := a.moderationTarget(ctx, opts)
+	if target == nil {
+		_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+		return
+	}
+	e, err := a.infractionsEmbed(ctx, target)
+	if err != nil {
+		logging.L().Error("infractions: lookup failed", "target", punishmentTargetLabel(target), "error", err)
+		out := "Could not load infractions, please try again later."
+		_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &out})
+		return
+	}
+	_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{e}})
+}
+
+func (a *App) infractionsEmbed(ctx context.Context, target *moderation.Punishment) (*discordgo.MessageEmbed, error) {
+	points, err := a.Punishments.Points(ctx, target.DiscordID, target.Player, a.infractionsSince())
+	if err != nil {
+		return nil, err
+	}
+	infractions, err := a.Punishments.Infractions(ctx, target.DiscordID, target.Player, infractionsShown)
+	if err != nil {
+		return nil, err
+	}
+	history, err := a.Punishments.History(ctx, target.DiscordID, target.Player, infractionsShown)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf("%s\n**Active points:** %d", punishmentTargetLabel(target), points)
+	if a.Cfg.InfractionDecay > 0 {
+		desc += fmt.Sprintf(" (last %s)", moderation.FormatDuration(a.Cfg.InfractionDecay))
+	}
+	if next := a.Ladder.Next(points); next != nil {
+		desc += fmt.Sprintf("\n**Next step:** %s at %d points", strings.ToLower(punishmentLabels[next.Kind]), next.Points)
+	}
+
+	items := make([]string, 0, len(infractions))
+	for _, inf := range infractions {
+		line := fmt.Sprintf("<t:%d:d> **+%d** %s", inf.CreatedAt.Unix(), inf.Points, strings.ReplaceAll(inf.Kind, "_", " "))
+		if inf.Detail != "" {
+			line += " — " + truncate(inf.Detail, 80)
+		}
+		items = append(items, line)
+	}
+	punishments := make([]string, 0, len(history))
+	for _, p := range history {
+		by := ternary(p.ModeratorID == moderation.AutoModerator, "automatic", "<@"+p.ModeratorID+">")
+		line := fmt.Sprintf("<t:%d:d> %s, %s", p.CreatedAt.Unix(), truncate(punishmentSummary(p), 120), by)
+		switch {
+		case p.LiftedBy == moderation.LiftedByExpiry:
+			line += " (expired)"
+		case !p.LiftedAt.IsZero():
+			line += " (lifted)"
+		case p.Active() && !p.ExpiresAt.IsZero():
+			line += fmt.Sprintf(" (until <t:%d:R>)", p.ExpiresAt.Unix())
+		}
+		punishments = append(punishments, line)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "Infractions",
+		Description: desc,
+		Color:       0xF59E0B,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Infractions", Value: fieldList(items)},
+			{Name: "Punishments", Value: fieldList(punishments)},
+		},
+	}, nil
+}
@@ -0,0 +1,93 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// mcReport is the body of a "report" EVT sent by the server's /report command.
+type mcReport struct {
+	ReporterUUID string  `json:"reporter_uuid"`
+	ReporterName string  `json:"reporter_name"`
+	Target       string  `json:"target"`
+	Reason       string  `json:"reason"`
+	X            float64 `json:"x"`
+	Y            float64 `json:"y"`
+	Z            float64 `json:"z"`
+	Dimension    string  `json:"dimension"`
+}
+
+// handleInGameReport files a report sent from Minecraft and confirms it to
+// the reporter in-game.
+func (a *App) handleInGameReport(body string) {
+	var ev mcReport
+	if err := json.Unmarshal([]byte(body), &ev); err != nil {
+		logging.L().Warn("handleInGameReport: malformed report event", "body", body, "error", err)
+		return
+	}
+	ev.ReporterName = strings.TrimSpace(ev.ReporterName)
+	ev.Target = strings.TrimSpace(ev.Target)
+	ev.Reason = strings.TrimSpace(ev.Reason)
+	if ev.ReporterName == "" || ev.Reason == "" {
+		logging.L().Warn("handleInGameReport: report without reporter or reason", "body", body)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	typ := ternary(ev.Target != "", "player", "other")
+	if channelID, _ := a.reportDestination(typ); channelID == "" {
+		logging.L().Warn("handleInGameReport: no report channel configured; report not delivered", "reporter", ev.ReporterName)
+		a.tellPlayer(ctx, ev.ReporterName, "Your report could not be delivered. Please contact staff on Discord.", "red")
+		return
+	}
+
+	r := &reports.Report{
+		ReporterName: ev.ReporterName,
+		Source:       reports.SourceMinecraft,
+		Type:         typ,
+		TargetPlayer: ev.Target,
+		Details:      truncate(ev.Reason, 1000),
+		Location:     fmt.Sprintf("%.0f, %.0f, %.0f (%s)", ev.X, ev.Y, ev.Z, ternary(ev.Dimension != "", ev.Dimension, "unknown")),
+	}
+	if ev.ReporterUUID != "" {
+		if e, err := a.WLStore.GetByUUID(ctx, whitelist.NormalizeUUID(ev.ReporterUUID)); err != nil {
+			logging.L().Warn("handleInGameReport: reporter lookup failed", "uuid", ev.ReporterUUID, "error", err)
+		} else if e != nil {
+			r.ReporterID = e.DiscordID
+		}
+	}
+	if ev.Target != "" {
+		r.TargetUUID = a.playerUUID(ctx, ev.Target)
+	}
+
+	if err := a.postReport(ctx, r); err != nil {
+		a.tellPlayer(ctx, ev.ReporterName, "Your report could not be delivered. Please try again or contact staff on Discord.", "red")
+		return
+	}
+	logging.L().Info("in-game report filed", "report", r.ID, "reporter", ev.ReporterName, "target", ev.Target)
+	a.tellPlayer(ctx, ev.ReporterName, fmt.Sprintf("Thanks! Your report #%d was sent to staff.", r.ID), "green")
+}
+
+// tellPlayer sends a private chat message to an online player.
+func (a *App) tellPlayer(ctx context.Context, name, text, color string) {
+	if !validPlayerName(name) {
+		logging.L().Warn("tellPlayer: invalid player name", "player", name)
+		return
+	}
+	msg, err := json.Marshal(map[string]string{"text": "[Rotaria] " + text, "color": color})
+	if err != nil {
+		return
+	}
+	if err := a.bridgeCommand(ctx, fmt.Sprintf("tellraw %s %s", name, msg)); err != nil {
+		logging.L().Warn("tellPlayer: failed", "player", name, "error", err)
+	}
+}
@@ -0,0 +1,184 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// validPlayerName is the check every player name passes before it is put
+// into a server command.
+func validPlayerName(name string) bool {
+	return whitelist.ValidUsername(name)
+}
+
+var punishmentLabels = map[string]string{
+	moderation.Warn:    "Warning",
+	moderation.Kick:    "Kick",
+	moderation.Mute:    "Mute",
+	moderation.TempBan: "Temp-ban",
+	moderation.Ban:     "Ban",
+}
+
+// punishmentSummary describes p in one line, e.g. "Temp-ban (3d): griefing".
+func punishmentSummary(p *moderation.Punishment) string {
+	label := punishmentLabels[p.Kind]
+	if !p.ExpiresAt.IsZero() {
+		label += " (" + moderation.FormatDuration(p.ExpiresAt.Sub(p.CreatedAt).Round(time.Minute)) + ")"
+	}
+	return label + ": " + p.Reason
+}
+
+// punish records p, then applies it on the Minecraft server and on the linked
+// Discord account. It returns one status line per step; the error is only set
+// when nothing was applied because the record could not be saved.
+func (a *App) punish(ctx context.Context, p *moderation.Punishment) ([]string, error) {
+	if p.Player != "" && !validPlayerName(p.Player) {
+		logging.L().Warn("punish: refusing invalid player name", "player", p.Player)
+		return nil, fmt.Errorf("invalid player name %q", p.Player)
+	}
+	if !p.ExpiresAt.IsZero() && p.ExpiresAt.Sub(p.CreatedAt) > moderation.MaxTimed {
+		return nil, fmt.Errorf("%s can last at most 28 days", strings.ToLower(punishmentLabels[p.Kind]))
+	}
+	// Record first so a timed punishment is always lifted, even if a step
+	// below fails half way.
+	if err := a.Punishments.Create(ctx, p); err != nil {
+		logging.L().Error("punish: saving punishment failed", "kind", p.Kind, "player", p.Player, "error", err)
+		return nil, err
+	}
+
+	var lines []string
+	step := func(name string, err error) {
+		if err != nil {
+			logging.L().Warn("punish: step failed", "punishment", p.ID, "step", name, "error", err)
+			lines = append(lines, fmt.Sprintf("❌ %s: %v", name, err))
+			return
+		}
+		lines = append(lines, "✅ "+name)
+	}
+
+	if p.Player != "" {
+		reason := strings.Join(strings.Fields(p.Reason), " ")
+		var cmd string
+		switch p.Kind {
+		case moderation.Warn:
+			msg, _ := json.Marshal(map[string]string{"text": "[Rotaria] Warning from staff: " + reason, "color": "red"})
+			cmd = fmt.Sprintf("tellraw %s %s", p.Player, msg)
+		case moderation.Kick:
+			cmd = fmt.Sprintf("kick %s %s", p.Player, reason)
+		case moderation.Mute:
+			cmd = fmt.Sprintf("mute %s %s", p.Player, reason)
+		case moderation.TempBan, moderation.Ban:
+			cmd = fmt.Sprintf("ban %s %s", p.Player, reason)
+		}
+		step("Minecraft "+strings.ToLower(punishmentLabels[p.Kind]), a.bridgeCommand(ctx, cmd))
+	}
+
+	if p.DiscordID != "" {
+		// DM before banning; a banned user shares no server with the bot.
+		step("Discord DM", a.dmPunishment(p))
+		switch p.Kind {
+		case moderation.Mute, moderation.TempBan:
+			until := p.ExpiresAt
+			step("Discord timeout", a.Session.GuildMemberTimeout(a.Cfg.GuildID, p.DiscordID, &until))
+		case moderation.Ban:
+			step("Discord ban", a.Session.GuildBanCreateWithReason(a.Cfg.GuildID, p.DiscordID, truncate(p.Reason, 512), 0))
+		}
+	}
+
+	a.audit(ctx, p.ModeratorID, "punishment_"+p.Kind, ternary(p.DiscordID != "", p.DiscordID, p.Player), punishmentSummary(p))
+	logging.L().Info("punishment applied", "punishment", p.ID, "kind", p.Kind, "player", p.Player, "discord_id", p.DiscordID, "moderator", p.ModeratorID)
+	return lines, nil
+}
+
+func (a *App) dmPunishment(p *moderation.Punishment) error {
+	var msg string
+	switch p.Kind {
+	case moderation.Warn:
+		msg = "⚠️ You received a warning from the Rotaria staff."
+	case moderation.Kick:
+		msg = "👢 You were kicked from the Rotaria server."
+	case moderation.Mute:
+		msg = fmt.Sprintf("🔇 You are muted on Rotaria until <t:%d:f>.", p.ExpiresAt.Unix())
+	case moderation.TempBan:
+		msg = fmt.Sprintf("⛔ You are banned from the Rotaria server until <t:%d:f>.", p.ExpiresAt.Unix())
+	case moderation.Ban:
+		msg = "⛔ You have been banned from Rotaria."
+	}
+	msg += "\n> Reason: " + p.Reason
+	dm, err := a.Session.UserChannelCreate(p.DiscordID)
+	if err != nil {
+		return err
+	}
+	_, err = a.Session.ChannelMessageSend(dm.ID, msg)
+	return err
+}
+
+// liftPunishment undoes p and marks it lifted. A server that cannot be
+// reached leaves p in force so the next attempt retries it.
+func (a *App) liftPunishment(ctx context.Context, p *moderation.Punishment, by string) error {
+	if err := a.undoPunishment(ctx, p); err != nil {
+		return err
+	}
+	if err := a.Punishments.Lift(ctx, p.ID, by); err != nil {
+		return err
+	}
+	a.audit(ctx, by, "punishment_lift", ternary(p.DiscordID != "", p.DiscordID, p.Player), punishmentSummary(p))
+	logging.L().Info("punishment lifted", "punishment", p.ID, "kind", p.Kind, "player", p.Player, "by", by)
+	return nil
+}
+
+// undoPunishment pardons or unmutes p on the server and removes the ban or
+// timeout on Discord. Discord errors are only logged; the server is the
+// part that must not be skipped.
+func (a *App) undoPunishment(ctx context.Context, p *moderation.Punishment) error {
+	var cmd string
+	switch p.Kind {
+	case moderation.TempBan, moderation.Ban:
+		cmd = "pardon"
+	case moderation.Mute:
+		cmd = "unmute"
+	default:
+		return errors.New("only bans and mutes can be lifted")
+	}
+	if p.Player != "" {
+		if err := a.playerCommand(ctx, cmd, p.Player); err != nil {
+			return fmt.Errorf("minecraft %s: %w", cmd, err)
+		}
+	}
+	if p.DiscordID != "" {
+		var err error
+		if p.Kind == moderation.Ban {
+			err = a.Session.GuildBanDelete(a.Cfg.GuildID, p.DiscordID)
+		} else {
+			err = a.Session.GuildMemberTimeout(a.Cfg.GuildID, p.DiscordID, nil)
+		}
+		if err != nil && !isNotFound(err) {
+			logging.L().Warn("undoPunishment: discord step failed", "punishment", p.ID, "discord_id", p.DiscordID, "error", err)
+		}
+	}
+	return nil
+}
+
+// liftExpiredPunishments lifts every timed punishment whose time is up. It
+// runs from the database, so punishments that ran out while the bot was
+// offline are lifted on the first check after a restart.
+func (a *App) liftExpiredPunishments(ctx context.Context) {
+	expired, err := a.Punishments.Expired(ctx, time.Now())
+	if err != nil {
+		logging.L().Error("liftExpiredPunishments: list failed", "error", err)
+		return
+	}
+	for _, p := range expired {
+		if err := a.liftPunishment(ctx, p, moderation.LiftedByExpiry); err != nil {
+			logging.L().Warn("liftExpiredPunishments: lift failed; will retry", "punishment", p.ID, "error", err)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package discord
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
@@ -18,8 +17,7 @@ func (a *App) onGuildMemberRemove(_ *discordgo.Session, ev *discordgo.GuildMembe
 		return
 	}
 	if a.Bridge.IsConnected() {
-
-		if _, err := a.Bridge.SendCommand(ctx, fmt.Sprintf("unwhitelist %s", entry.Username)); err != nil {
+		if err := a.playerCommand(ctx, "unwhitelist", entry.Username); err != nil {
 			logging.L().Error("failed to unwhitelist user on bridge", "username", entry.Username, "error", err)
 		}
 	}
@@ -19,35 +19,111 @@ func (a *App) onInteraction(s *discordgo.Session, i *discordgo.InteractionCreate
 		case "whitelist":
 			a.openWhitelistModal(i)
 		case "report":
-			a.openReportModal(i)
+			a.openReportCategories(i)
 		case "lookup":
 			a.handleLookup(i)
 		case "forceupdateusername":
 			a.handleForceUpdate(i)
+		case "reconcile":
+			a.handleReconcileCommand(i)
+		case "backup":
+			a.handleBackupCommand(i)
+		case "setup":
+			a.handleSetupCommand(i)
+		case "wl":
+			a.handleWLCommand(i)
+		case "reports":
+			a.handleReportsCommand(i)
+		case "reportupdates":
+			a.handleReportUpdatesCommand(i)
+		case "kick", "mute", "tempban", "ban", "unban":
+			a.handlePunishCommand(i)
+		case "infractions":
+			a.handleInfractionsCommand(i)
+		case reportMessageCommand:
+			a.openReportMessageModal(i)
+		case minecraftAccountCommand:
+			a.handleMinecraftAccountCommand(i)
 		}
 	case discordgo.InteractionModalSubmit:
 		cid := i.ModalSubmitData().CustomID
+		action := componentAction(cid)
 		switch {
-		case cid == "whitelist_modal":
+		case action == "whitelist_modal":
 			a.handleWhitelistSubmit(i)
-		case cid == "report_modal":
+		case action == "report_msg_modal":
+			a.handleReportMessageSubmit(i)
+		case action == "report_modal":
 			a.handleReportSubmit(i)
-		case strings.HasPrefix(cid, "report_action_modal|"):
+		case action == "wl_reason_modal":
+			a.handleRejectModal(i)
+		case strings.HasPrefix(action, "report_mod_modal_"):
+			a.handleReportModerationModal(i)
+		case action == "report_resolve_modal", action == "report_dismiss_modal":
 			a.handleReportActionModal(i)
 		}
 	case discordgo.InteractionMessageComponent:
-		c := i.MessageComponentData().CustomID
-		switch {
-		case c == "request_whitelist":
-			a.openWhitelistModal(i)
-		case strings.HasPrefix(c, "report_resolve_"), strings.HasPrefix(c, "report_dismiss_"):
-			a.openReportActionModal(i)
-		case strings.HasPrefix(c, "approve_"), strings.HasPrefix(c, "reject_"):
-			a.handleWhitelistDecision(i)
+		if h := a.componentHandler(i.MessageComponentData().CustomID); h != nil {
+			h(i)
 		}
 	}
 }
 
+// componentHandler returns the handler of a button or select menu, or nil
+// for unknown CustomIDs.
+func (a *App) componentHandler(c string) func(*discordgo.InteractionCreate) {
+	action := componentAction(c)
+	switch {
+	case c == "request_whitelist":
+		return a.openWhitelistModal
+	case c == "request_report":
+		return a.openReportCategories
+	case c == "report_category":
+		return a.handleReportCategorySelect
+	case action == "wl_list_prev", action == "wl_list_next":
+		return a.handleWLListButton
+	case c == "rules_accept":
+		return a.handleRulesAccept
+	case action == "whitelist_step":
+		return a.handleWhitelistStepButton
+	case action == "report_resolve", action == "report_dismiss":
+		return a.openReportActionModal
+	case strings.HasPrefix(action, "report_mod_"):
+		return a.openReportModerationModal
+	case c == "report_claim":
+		return a.handleReportClaim
+	case c == "report_optout":
+		return a.handleReportOptOut
+	case action == "approve", action == "reject", action == "veto":
+		return a.handleWhitelistDecision
+	case action == "approval_retry", action == "approval_rollback":
+		return a.handleApprovalButton
+	case action == "wl_reason":
+		return a.handleRejectReasonSelect
+	case action == "interview":
+		return a.handleInterviewButton
+	case action == "wl_transfer", action == "wl_abort":
+		return a.handleWhitelistConflict
+	case action == "reconcile_fix", action == "reconcile_dismiss":
+		return a.handleReconcileAction
+
+	// Buttons on messages posted before component state.
+	case strings.HasPrefix(c, "report_resolve_"), strings.HasPrefix(c, "report_dismiss_"):
+		return a.openReportActionModal
+	case strings.HasPrefix(c, "report_mod|"):
+		return a.openReportModerationModal
+	case strings.HasPrefix(c, "approve_"), strings.HasPrefix(c, "reject_"), strings.HasPrefix(c, "veto_"):
+		return a.handleWhitelistDecision
+	case strings.HasPrefix(c, "approval_retry|"), strings.HasPrefix(c, "approval_rollback|"):
+		return a.handleApprovalButton
+	case strings.HasPrefix(c, "interview_"):
+		return a.handleInterviewButton
+	case strings.HasPrefix(c, "wl_transfer_"), strings.HasPrefix(c, "wl_abort_"):
+		return a.handleWhitelistConflict
+	}
+	return nil
+}
+
 func (a *App) handleListCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
@@ -93,3 +169,14 @@ func (a *App) reply(i *discordgo.InteractionCreate, msg string, eph bool) {
 		Data: &discordgo.InteractionResponseData{Content: msg, Flags: flags},
 	})
 }
+
+// followup sends a message after the interaction has already been acknowledged.
+func (a *App) followup(i *discordgo.InteractionCreate, msg string, eph bool) {
+	flags := discordgo.MessageFlags(0)
+	if eph {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	if _, err := a.Session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: msg, Flags: flags}); err != nil {
+		logging.L().Warn("followup failed", "error", err)
+	}
+}
@@ -0,0 +1,51 @@
+package discord
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+)
+
+func handlerName(h func(*discordgo.InteractionCreate)) string {
+	if h == nil {
+		return "<nil>"
+	}
+	return runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+}
+
+func TestComponentHandler(t *testing.T) {
+	a := &App{}
+	tests := []struct {
+		customID string
+		want     func(*discordgo.InteractionCreate)
+	}{
+		{components.ID("reconcile_fix", "k1"), a.handleReconcileAction},
+		{components.ID("reconcile_dismiss", "k1"), a.handleReconcileAction},
+		{components.ID("approve", "k1"), a.handleWhitelistDecision},
+		{components.ID("reject", "k1"), a.handleWhitelistDecision},
+		{components.ID("veto", "k1"), a.handleWhitelistDecision},
+		{components.ID("interview", "k1"), a.handleInterviewButton},
+		{components.ID("wl_transfer", "k1"), a.handleWhitelistConflict},
+		{components.ID("wl_abort", "k1"), a.handleWhitelistConflict},
+		{components.ID("approval_retry", "k1"), a.handleApprovalButton},
+		{components.ID("approval_rollback", "k1"), a.handleApprovalButton},
+		{components.ID("wl_list_next", "k1"), a.handleWLListButton},
+		{components.ID("whitelist_step", "k1"), a.handleWhitelistStepButton},
+		{components.ID("wl_reason", "k1"), a.handleRejectReasonSelect},
+		{components.ID("report_resolve", "k1"), a.openReportActionModal},
+		{components.ID("report_mod_ban", "k1"), a.openReportModerationModal},
+		{"report_claim", a.handleReportClaim},
+		{"request_whitelist", a.openWhitelistModal},
+		{"approve_Steve|123", a.handleWhitelistDecision},
+		{"report_mod|ban|Steve|123", a.openReportModerationModal},
+		{"unknown", nil},
+	}
+	for _, tt := range tests {
+		if got, want := handlerName(a.componentHandler(tt.customID)), handlerName(tt.want); got != want {
+			t.Errorf("componentHandler(%q) = %s, want %s", tt.customID, got, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
 )
 
@@ -24,6 +25,7 @@ func (a *App) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate)
 	if a.Blacklist != nil && a.Blacklist.Contains(m.Content) {
 		logging.L().Info("Blocked message from user (blacklist hit)", "message", m.Content, "user", m.Author.ID)
 		_ = s.ChannelMessageDelete(m.ChannelID, m.ID)
+		go a.recordInfraction(&moderation.Infraction{DiscordID: m.Author.ID, Kind: moderation.BlacklistDiscord, Detail: truncate(m.Content, 200)})
 		return
 	}
 
@@ -0,0 +1,205 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// punishCommandKinds maps the punishment commands to what they apply.
+var punishCommandKinds = map[string]string{
+	"kick":    moderation.Kick,
+	"mute":    moderation.Mute,
+	"tempban": moderation.TempBan,
+	"ban":     moderation.Ban,
+}
+
+func newPunishCommand(name, description string, perm int64) *discordgo.ApplicationCommand {
+	kind := punishCommandKinds[name]
+	// Discord wants required options first.
+	var opts []*discordgo.ApplicationCommandOption
+	if kind != "" {
+		opts = append(opts, &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: "reason", Description: "Reason, shown to the player", Required: true, MaxLength: 500})
+	}
+	if kind == moderation.Mute || kind == moderation.TempBan {
+		opts = append(opts, &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: "duration", Description: "How long, e.g. 30m, 12h, 3d or 2w", Required: true, MaxLength: 16})
+	}
+	opts = append(opts,
+		&discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Discord user", Required: false},
+		&discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: "player", Description: "Minecraft username", Required: false, MaxLength: 16},
+	)
+	return &discordgo.ApplicationCommand{
+		Name:                     name,
+		Description:              description,
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options:                  opts,
+	}
+}
+
+// handlePunishCommand runs /kick, /mute, /tempban, /ban and /unban.
+func (a *App) handlePunishCommand(i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range data.Options {
+		opts[o.Name] = o
+	}
+	if opts["user"] == nil && opts["player"] == nil {
+		a.reply(i, "Provide one option: user or player", true)
+		return
+	}
+
+	kind := punishCommandKinds[data.Name]
+	var duration time.Duration
+	if o, ok := opts["duration"]; ok {
+		d, err := moderation.ParseDuration(o.StringValue())
+		if err != nil {
+			a.reply(i, err.Error(), true)
+			return
+		}
+		if d > moderation.MaxTimed {
+			a.reply(i, "Mutes and temp-bans can last at most 28 days, Discord's longest timeout.", true)
+			return
+		}
+		duration = d
+	}
+
+	s := a.Session
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := make([]byte, 8192)
+				n := runtime.Stack(stack, false)
+				logging.L().Error("punish panic", "command", data.Name, "recover", r, "stack", string(stack[:n]))
+				safe := "internal error during /" + data.Name
+				_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &safe})
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var response string
+		p, msg := a.moderationTarget(ctx, opts)
+		switch {
+		case p == nil:
+			response = msg
+		case kind == "":
+			response = a.unban(ctx, i.Member.User.ID, p)
+		case kind == moderation.Kick && p.Player == "":
+			response = fmt.Sprintf("<@%s> is not linked to a Minecraft account.", p.DiscordID)
+		default:
+			p.Kind = kind
+			p.Reason = strings.TrimSpace(opts["reason"].StringValue())
+			p.ModeratorID = i.Member.User.ID
+			p.CreatedAt = time.Now()
+			if duration > 0 {
+				p.ExpiresAt = p.CreatedAt.Add(duration)
+			}
+			lines, err := a.punish(ctx, p)
+			if err != nil {
+				response = "Could not record the punishment; nothing was applied. Please try again."
+			} else {
+				response = punishmentResult(p, lines)
+			}
+		}
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &response})
+	}()
+}
+
+// moderationTarget resolves the user or player option to both identities
+// through the whitelist. Either may stay empty when the account is not
+// linked. When it returns nil the string explains why.
+func (a *App) moderationTarget(ctx context.Context, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) (*moderation.Punishment, string) {
+	p := &moderation.Punishment{}
+	if o, ok := opts["user"]; ok {
+		p.DiscordID = o.UserValue(nil).ID
+		e, err := a.WLStore.GetByDiscord(ctx, p.DiscordID)
+		if err != nil {
+			logging.L().Error("moderationTarget: lookup failed", "discord_id", p.DiscordID, "error", err)
+			return nil, fmt.Sprintf("Lookup failed for <@%s>, please try again later.", p.DiscordID)
+		}
+		if e != nil {
+			p.Player, p.PlayerUUID = e.Username, e.MinecraftUUID
+		}
+		return p, ""
+	}
+
+	p.Player = strings.TrimSpace(opts["player"].StringValue())
+	if !validPlayerName(p.Player) {
+		return nil, fmt.Sprintf("`%s` is not a valid Minecraft username.", p.Player)
+	}
+	e, err := a.WLStore.GetByUsername(ctx, p.Player)
+	if err != nil {
+		logging.L().Error("moderationTarget: lookup failed", "player", p.Player, "error", err)
+		return nil, fmt.Sprintf("Lookup failed for `%s`, please try again later.", p.Player)
+	}
+	if e != nil {
+		p.Player, p.PlayerUUID, p.DiscordID = e.Username, e.MinecraftUUID, e.DiscordID
+	} else {
+		p.PlayerUUID = a.playerUUID(ctx, p.Player)
+	}
+	return p, ""
+}
+
+// unban lifts every ban in force against the target. A target without a
+// recorded ban is still pardoned, e.g. when it was banned by hand.
+func (a *App) unban(ctx context.Context, actorID string, target *moderation.Punishment) string {
+	label := punishmentTargetLabel(target)
+	active, err := a.Punishments.Active(ctx, target.DiscordID, target.Player, moderation.TempBan, moderation.Ban)
+	if err != nil {
+		logging.L().Error("unban: lookup failed", "target", label, "error", err)
+		return "Could not load the bans, please try again later."
+	}
+	if len(active) == 0 {
+		target.Kind = moderation.Ban
+		if err := a.undoPunishment(ctx, target); err != nil {
+			return fmt.Sprintf("❌ Could not unban %s: %v", label, err)
+		}
+		a.audit(ctx, actorID, "punishment_lift", ternary(target.DiscordID != "", target.DiscordID, target.Player), "unrecorded ban")
+		return fmt.Sprintf("✅ Unbanned %s. No ban was recorded by the bot.", label)
+	}
+
+	var lines []string
+	for _, p := range active {
+		if err := a.liftPunishment(ctx, p, actorID); err != nil {
+			logging.L().Warn("unban: lift failed", "punishment", p.ID, "error", err)
+			lines = append(lines, fmt.Sprintf("❌ %s: %v", punishmentSummary(p), err))
+			continue
+		}
+		lines = append(lines, "✅ Lifted "+punishmentSummary(p))
+	}
+	return fmt.Sprintf("Unban %s:\n%s", label, strings.Join(lines, "\n"))
+}
+
+func punishmentTargetLabel(p *moderation.Punishment) string {
+	var parts []string
+	if p.Player != "" {
+		parts = append(parts, "`"+p.Player+"`")
+	}
+	if p.DiscordID != "" {
+		parts = append(parts, "<@"+p.DiscordID+">")
+	}
+	return strings.Join(parts, " / ")
+}
+
+// punishmentResult is the moderator's confirmation for an applied punishment.
+func punishmentResult(p *moderation.Punishment, lines []string) string {
+	out := fmt.Sprintf("%s applied to %s", punishmentLabels[p.Kind], punishmentTargetLabel(p))
+	if !p.ExpiresAt.IsZero() {
+		out += fmt.Sprintf(" until <t:%d:f>", p.ExpiresAt.Unix())
+	}
+	return out + ":\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,223 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/reconcile"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+func newReconcileCommand(perm int64) *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:                     "reconcile",
+		Description:              "Compare the server whitelist with the database",
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+	}
+}
+
+func (a *App) handleReconcileCommand(i *discordgo.InteractionCreate) {
+	s := a.Session
+	if !a.Bridge.IsConnected() {
+		a.reply(i, "Minecraft not connected.", true)
+		return
+	}
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := make([]byte, 8192)
+				n := runtime.Stack(stack, false)
+				logging.L().Error("reconcile panic", "recover", r, "stack", string(stack[:n]))
+				safe := "internal error during reconcile"
+				_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &safe})
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		rep, err := a.Reconciler.Run(ctx)
+		if err != nil {
+			logging.L().Error("reconcile failed", "error", err)
+			msg := fmt.Sprintf("Reconcile failed: %v", err)
+			_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+			return
+		}
+		if rep.Empty() {
+			msg := "No drift: server whitelist and database agree."
+			_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+			return
+		}
+		msg := "Drift report posted to the staff channel."
+		if err := a.postDriftReport(rep); err != nil {
+			msg = fmt.Sprintf("Drift found but the report could not be posted: %v", err)
+		}
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+	}()
+}
+
+func (a *App) scheduledReconcile(ctx context.Context) {
+	if !a.Bridge.IsConnected() {
+		logging.L().Debug("scheduledReconcile: minecraft not connected; skipping")
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	rep, err := a.Reconciler.Run(ctx)
+	if err != nil {
+		logging.L().Error("scheduledReconcile: run failed", "error", err)
+		return
+	}
+	if rep.Empty() {
+		logging.L().Debug("scheduledReconcile: no drift")
+		return
+	}
+	if err := a.postDriftReport(rep); err != nil {
+		logging.L().Error("scheduledReconcile: post failed", "error", err)
+	}
+}
+
+func (a *App) postDriftReport(rep reconcile.Report) error {
+	if a.Cfg.StaffChannelID == "" {
+		logging.L().Warn("postDriftReport: StaffChannelID not configured; drift report not delivered",
+			"server_only", len(rep.ServerOnly),
+			"db_only", len(rep.DBOnly),
+			"renamed", len(rep.Renamed),
+		)
+		return fmt.Errorf("staff channel not configured")
+	}
+	// The buttons apply exactly the report staff reviewed, not a fresh run.
+	key := a.putComponentState(context.Background(), driftReportState, rep)
+	buttons := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{CustomID: components.ID("reconcile_fix", key), Label: "Apply fixes", Style: discordgo.DangerButton},
+			discordgo.Button{CustomID: components.ID("reconcile_dismiss", key), Label: "Dismiss", Style: discordgo.SecondaryButton},
+		}},
+	}
+	_, err := a.Session.ChannelMessageSendComplex(a.Cfg.StaffChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{driftEmbed(rep)},
+		Components: buttons,
+	})
+	return err
+}
+
+func driftEmbed(rep reconcile.Report) *discordgo.MessageEmbed {
+	var serverOnly, dbOnly, renamed []string
+	for _, p := range rep.ServerOnly {
+		serverOnly = append(serverOnly, fmt.Sprintf("`%s` (`%s`)", p.Name, p.UUID))
+	}
+	for _, e := range rep.DBOnly {
+		dbOnly = append(dbOnly, fmt.Sprintf("`%s` <@%s>", e.Username, e.DiscordID))
+	}
+	for _, r := range rep.Renamed {
+		renamed = append(renamed, fmt.Sprintf("`%s` → `%s` <@%s>", r.Entry.Username, r.NewName, r.Entry.DiscordID))
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{Name: fmt.Sprintf("In-game only (%d)", len(serverOnly)), Value: fieldList(serverOnly)},
+		{Name: fmt.Sprintf("Database only (%d)", len(dbOnly)), Value: fieldList(dbOnly)},
+		{Name: fmt.Sprintf("Renamed (%d)", len(renamed)), Value: fieldList(renamed)},
+	}
+	if len(rep.Unresolved) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("Unresolved names (%d)", len(rep.Unresolved)),
+			Value: fieldList(rep.Unresolved),
+		})
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "Whitelist Drift Report",
+		Description: "The Minecraft server whitelist and the database disagree. \"Apply fixes\" applies the changes listed here so the server matches the database.",
+		Color:       0xF59E0B,
+		Fields:      fields,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: "Rotaria Whitelist"},
+	}
+}
+
+func (a *App) handleReconcileAction(i *discordgo.InteractionCreate) {
+	if !hasPermission(i, discordgo.PermissionAdministrator) {
+		a.reply(i, "Only administrators can act on drift reports.", true)
+		return
+	}
+	if len(i.Message.Embeds) == 0 {
+		a.reply(i, "Missing embed.", true)
+		return
+	}
+	cp := *i.Message.Embeds[0]
+
+	cid := i.MessageComponentData().CustomID
+	if componentAction(cid) == "reconcile_dismiss" {
+		cp.Description += fmt.Sprintf("\n\n📝 Dismissed by <@%s>.", i.Member.User.ID)
+		cp.Color = 0x6B7280
+		_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{&cp}, Components: []discordgo.MessageComponent{}},
+		})
+		return
+	}
+
+	var rep reconcile.Report
+	if err := a.componentState(context.Background(), cid, driftReportState, &rep); err != nil {
+		if errors.Is(err, components.ErrNotFound) || errors.Is(err, components.ErrExpired) {
+			a.reply(i, "This drift report is outdated. Run /reconcile again and review the new report.", true)
+			return
+		}
+		a.replyComponentStateError(i, err)
+		return
+	}
+	if !a.Bridge.IsConnected() {
+		a.reply(i, "Minecraft not connected.", true)
+		return
+	}
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	errs := a.Reconciler.Fix(ctx, rep)
+	logging.L().Info("reconcile fixes applied",
+		"moderator", i.Member.User.ID,
+		"server_only", len(rep.ServerOnly),
+		"db_only", len(rep.DBOnly),
+		"renamed", len(rep.Renamed),
+		"errors", len(errs),
+	)
+
+	line := fmt.Sprintf("📝 Fixes applied by <@%s>.", i.Member.User.ID)
+	cp.Color = 0x22C55E
+	if len(errs) > 0 {
+		var msgs []string
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		line = fmt.Sprintf("📝 Fixes applied by <@%s> with %d error(s).", i.Member.User.ID, len(errs))
+		cp.Fields = append(append([]*discordgo.MessageEmbedField(nil), cp.Fields...), &discordgo.MessageEmbedField{Name: "Fix errors", Value: fieldList(msgs)})
+		cp.Color = 0xEF4444
+	}
+	if strings.TrimSpace(cp.Description) == "" {
+		cp.Description = line
+	} else {
+		cp.Description += "\n\n" + line
+	}
+	cp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	embeds := []*discordgo.MessageEmbed{&cp}
+	buttons := []discordgo.MessageComponent{}
+	_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &embeds, Components: &buttons})
+}
@@ -0,0 +1,225 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// openRejectReasons answers a Reject or Veto click with an ephemeral picker
+// of preset reasons. The picker's state holds the vote and the request
+// message ID because the modal opened from it no longer sees the request
+// message.
+func (a *App) openRejectReasons(i *discordgo.InteractionCreate, vote string) {
+	var options []discordgo.SelectMenuOption
+	for n, r := range a.Cfg.RejectionReasons {
+		if n == 24 {
+			break
+		}
+		options = append(options, discordgo.SelectMenuOption{Label: truncate(r, 100), Value: strconv.Itoa(n)})
+	}
+	options = append(options, discordgo.SelectMenuOption{Label: "Custom…", Value: "custom"})
+
+	err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: ternary(vote == applications.VoteVeto, "Why are you vetoing this application?", "Why are you rejecting this application?"),
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    components.ID("wl_reason", a.putComponentState(context.Background(), rejectReasonState, rejectReasonPayload{Vote: vote, MessageID: i.Message.ID})),
+						Placeholder: "Choose a reason",
+						Options:     options,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logging.L().Error("openRejectReasons: respond failed", "error", err)
+	}
+}
+
+// handleRejectReasonSelect opens the reason modal prefilled with the chosen
+// preset so it can be adjusted before it is sent to the applicant.
+func (a *App) handleRejectReasonSelect(i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	if len(data.Values) == 0 {
+		return
+	}
+	_, key, _ := components.Split(data.CustomID)
+	var preset string
+	if n, err := strconv.Atoi(data.Values[0]); err == nil && n >= 0 && n < len(a.Cfg.RejectionReasons) {
+		preset = a.Cfg.RejectionReasons[n]
+	}
+
+	err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: components.ID("wl_reason_modal", key),
+			Title:    "Reject application",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "reason",
+						Label:     "Reason (sent to the applicant)",
+						Style:     discordgo.TextInputParagraph,
+						Value:     preset,
+						Required:  true,
+						MaxLength: 500,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "note",
+						Label:     "Staff note (not sent)",
+						Style:     discordgo.TextInputParagraph,
+						Required:  false,
+						MaxLength: 500,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logging.L().Error("handleRejectReasonSelect: modal failed", "error", err)
+	}
+}
+
+// handleRejectModal casts the rejection or veto and, once the request is
+// decided, rejects it with the given reason.
+func (a *App) handleRejectModal(i *discordgo.InteractionCreate) {
+	var st rejectReasonPayload
+	if err := a.componentState(context.Background(), i.ModalSubmitData().CustomID, rejectReasonState, &st); err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	vote, messageID := st.Vote, st.MessageID
+	reason := modalValue(i, "reason")
+	note := modalValue(i, "note")
+
+	msg, err := a.Session.ChannelMessage(i.ChannelID, messageID)
+	if err != nil || len(msg.Embeds) == 0 {
+		logging.L().Error("handleRejectModal: request message lookup failed", "message", messageID, "error", err)
+		a.reply(i, "Could not load the whitelist request, it may have been deleted.", true)
+		return
+	}
+	username, requesterID, ok := a.decisionTarget(context.Background(), msg)
+	if !ok {
+		a.reply(i, "This request was already decided.", true)
+		return
+	}
+
+	if a.quorumEnabled() {
+		switch a.castVote(i, msg, vote) {
+		case voteFailed:
+			return
+		case votePending, voteApproved:
+			embeds := msg.Embeds
+			if _, err := a.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+				Channel:    msg.ChannelID,
+				ID:         msg.ID,
+				Embeds:     &embeds,
+				Components: &msg.Components,
+			}); err != nil {
+				logging.L().Error("handleRejectModal: tally update failed", "message", msg.ID, "error", err)
+			}
+			a.updateReasonPicker(i, "Vote recorded. Your reason will be sent if the application is rejected.")
+			return
+		}
+	}
+
+	a.rejectApplication(context.Background(), msg, username, requesterID, i.Member.User.ID, reason, note)
+	a.updateReasonPicker(i, fmt.Sprintf("Rejected `%s`.", username))
+}
+
+// rejectApplication closes the request message, stores the reason, starts
+// the reapply cooldown and tells the applicant.
+func (a *App) rejectApplication(ctx context.Context, msg *discordgo.Message, username, requesterID, moderatorID, reason, note string) {
+	cp := decisionEmbed(msg.Embeds[0], username, requesterID, moderatorID, false)
+	setEmbedField(cp, "Reason", truncate(reason, 1024), false)
+	if note != "" {
+		setEmbedField(cp, "Staff note", truncate(note, 1024), false)
+	}
+	embeds := []*discordgo.MessageEmbed{cp}
+	components := []discordgo.MessageComponent{}
+	if _, err := a.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    msg.ChannelID,
+		ID:         msg.ID,
+		Embeds:     &embeds,
+		Components: &components,
+	}); err != nil {
+		logging.L().Error("rejectApplication: embed update failed", "message", msg.ID, "error", err)
+	}
+
+	if app := a.recordDecision(msg.ID, moderatorID, applications.StatusRejected); app != nil {
+		if err := a.Applications.SetReason(ctx, app.ID, reason, note); err != nil {
+			logging.L().Error("rejectApplication: saving reason failed", "application", app.ID, "error", err)
+		}
+	}
+
+	dm := fmt.Sprintf("❌ Your whitelist application for `%s` was rejected.\nReason: %s", username, reason)
+	if d := a.Cfg.PolicyReapplyCooldown; d > 0 {
+		until := time.Now().Add(d)
+		if err := a.Applications.SetCooldown(ctx, requesterID, until, reason); err != nil {
+			logging.L().Error("rejectApplication: saving cooldown failed", "discord_id", requesterID, "error", err)
+		}
+		dm += fmt.Sprintf("\nYou can apply again <t:%d:R>.", until.Unix())
+	}
+	if ch, err := a.Session.UserChannelCreate(requesterID); err == nil {
+		if _, err := a.Session.ChannelMessageSend(ch.ID, dm); err != nil {
+			logging.L().Warn("rejectApplication: DM failed", "discord_id", requesterID, "error", err)
+		}
+	}
+}
+
+// updateReasonPicker replaces the ephemeral reason picker with a short status.
+func (a *App) updateReasonPicker(i *discordgo.InteractionCreate, msg string) {
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+// decisionTarget reads the applicant behind the Approve button of a request
+// message. It fails once the buttons have been removed by a decision.
+func (a *App) decisionTarget(ctx context.Context, msg *discordgo.Message) (username, requesterID string, ok bool) {
+	for _, row := range msg.Components {
+		var buttons []discordgo.MessageComponent
+		switch r := row.(type) {
+		case *discordgo.ActionsRow:
+			buttons = r.Components
+		case discordgo.ActionsRow:
+			buttons = r.Components
+		}
+		for _, c := range buttons {
+			var id string
+			switch b := c.(type) {
+			case *discordgo.Button:
+				id = b.CustomID
+			case discordgo.Button:
+				id = b.CustomID
+			}
+			if componentAction(id) == "approve" || strings.HasPrefix(id, "approve_") {
+				d, err := a.decision(ctx, id)
+				if err != nil {
+					logging.L().Warn("decisionTarget: state lookup failed", "message", msg.ID, "error", err)
+					return "", "", false
+				}
+				return d.Username, d.RequesterID, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,189 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// reportModerationRow holds the punishment buttons of an open player report.
+// key is the state key of the report's other buttons.
+func reportModerationRow(key string) discordgo.ActionsRow {
+	return discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		&discordgo.Button{CustomID: components.ID("report_mod_"+moderation.Warn, key), Label: "Warn", Style: discordgo.SecondaryButton},
+		&discordgo.Button{CustomID: components.ID("report_mod_"+moderation.Kick, key), Label: "Kick", Style: discordgo.PrimaryButton},
+		&discordgo.Button{CustomID: components.ID("report_mod_"+moderation.TempBan, key), Label: "Temp-ban", Style: discordgo.DangerButton},
+		&discordgo.Button{CustomID: components.ID("report_mod_"+moderation.Ban, key), Label: "Ban", Style: discordgo.DangerButton},
+	}}
+}
+
+// moderatedReport loads the open report behind a moderation button or modal.
+// When it returns nil the interaction has been answered.
+func (a *App) moderatedReport(ctx context.Context, i *discordgo.InteractionCreate, customID string) *reports.Report {
+	if !a.reportStaff(i) {
+		return nil
+	}
+	var (
+		r   *reports.Report
+		err error
+	)
+	if _, _, ok := components.Split(customID); ok {
+		var st reportPayload
+		if err := a.componentState(ctx, customID, reportState, &st); err != nil {
+			a.replyComponentStateError(i, err)
+			return nil
+		}
+		r, err = a.Reports.Get(ctx, st.ReportID)
+	} else if i.Message != nil {
+		// Buttons posted before component state: "report_mod|<kind>".
+		r, err = a.Reports.GetByMessage(ctx, i.Message.ID)
+	}
+	if err != nil {
+		logging.L().Error("moderatedReport: lookup failed", "custom_id", customID, "error", err)
+	}
+	switch {
+	case r == nil:
+		a.reply(i, "This report is not tracked.", true)
+	case r.Status != reports.StatusOpen:
+		a.reply(i, fmt.Sprintf("Report #%d was already %s.", r.ID, r.Status), true)
+	case r.TargetPlayer == "":
+		a.reply(i, fmt.Sprintf("Report #%d does not name a player.", r.ID), true)
+	case !validPlayerName(r.TargetPlayer):
+		// The reporter typed the name; it must not reach a server command.
+		a.reply(i, fmt.Sprintf("Report #%d names `%s`, which is not a valid Minecraft username. Use the punishment commands instead.", r.ID, r.TargetPlayer), true)
+	default:
+		return r
+	}
+	return nil
+}
+
+func (a *App) openReportModerationModal(i *discordgo.InteractionCreate) {
+	cid := i.MessageComponentData().CustomID
+	kind := strings.TrimPrefix(strings.TrimPrefix(componentAction(cid), "report_mod_"), "report_mod|")
+	if _, ok := punishmentLabels[kind]; !ok {
+		return
+	}
+	ctx := context.Background()
+	r := a.moderatedReport(ctx, i, cid)
+	if r == nil {
+		return
+	}
+	_, key, ok := components.Split(cid)
+	if !ok {
+		key = a.putComponentState(ctx, reportState, reportPayload{ReportID: r.ID, ReporterID: r.ReporterID})
+	}
+
+	rows := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.TextInput{CustomID: "reason", Label: "Reason (shown to the player)", Style: discordgo.TextInputParagraph, Required: true, MaxLength: 500},
+		}},
+	}
+	if kind == moderation.TempBan {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.TextInput{CustomID: "duration", Label: "Duration (e.g. 12h, 3d, 2w)", Style: discordgo.TextInputShort, Required: true, MaxLength: 16},
+		}})
+	}
+	rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		&discordgo.TextInput{CustomID: "public_note", Label: "Message to reporter (optional)", Style: discordgo.TextInputParagraph, Required: false, MaxLength: 500},
+	}})
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   components.ID("report_mod_modal_"+kind, key),
+			Title:      truncate(fmt.Sprintf("%s %s", punishmentLabels[kind], r.TargetPlayer), 45),
+			Components: rows,
+		},
+	})
+}
+
+// handleReportModerationModal punishes the reported player and resolves the
+// report with the action as its note.
+func (a *App) handleReportModerationModal(i *discordgo.InteractionCreate) {
+	cid := i.ModalSubmitData().CustomID
+	kind := strings.TrimPrefix(componentAction(cid), "report_mod_modal_")
+	if _, ok := punishmentLabels[kind]; !ok {
+		return
+	}
+	ctx := context.Background()
+	r := a.moderatedReport(ctx, i, cid)
+	if r == nil {
+		return
+	}
+	p := &moderation.Punishment{
+		Kind:        kind,
+		Player:      r.TargetPlayer,
+		PlayerUUID:  r.TargetUUID,
+		Reason:      strings.TrimSpace(modalValue(i, "reason")),
+		ModeratorID: i.Member.User.ID,
+		ReportID:    r.ID,
+		CreatedAt:   time.Now(),
+	}
+	if kind == moderation.TempBan {
+		d, err := moderation.ParseDuration(modalValue(i, "duration"))
+		if err != nil {
+			a.reply(i, err.Error(), true)
+			return
+		}
+		if d > moderation.MaxTimed {
+			a.reply(i, "Temp-bans can last at most 28 days, Discord's longest timeout.", true)
+			return
+		}
+		p.ExpiresAt = p.CreatedAt.Add(d)
+	}
+
+	// The server commands can outlive the 3s interaction window.
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		logging.L().Error("handleReportModerationModal: defer failed", "error", err)
+		return
+	}
+
+	p.DiscordID = a.linkedDiscordID(ctx, p.Player, p.PlayerUUID)
+	lines, err := a.punish(ctx, p)
+	if err != nil {
+		out := "Could not record the punishment; nothing was applied. Please try again."
+		_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &out})
+		return
+	}
+
+	note := punishmentSummary(p)
+	publicNote := strings.TrimSpace(modalValue(i, "public_note"))
+	out := punishmentResult(p, lines)
+	if err := a.Reports.Close(ctx, r.ID, reports.StatusResolved, i.Member.User.ID, note, publicNote); errors.Is(err, reports.ErrClosed) {
+		out += fmt.Sprintf("\nReport #%d was already closed.", r.ID)
+	} else if err != nil {
+		logging.L().Error("handleReportModerationModal: closing report failed", "report", r.ID, "error", err)
+		out += fmt.Sprintf("\n⚠️ Report #%d could not be marked resolved.", r.ID)
+	} else {
+		r.Status, r.ClosedBy, r.Notes, r.PublicNote, r.UpdatedAt = reports.StatusResolved, i.Member.User.ID, note, publicNote, time.Now()
+		a.refreshReportMessage(r)
+		out += fmt.Sprintf("\nReport #%d resolved.%s", r.ID, a.notifyReporter(ctx, r))
+	}
+	_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &out})
+}
+
+// linkedDiscordID returns the Discord account whitelisted for a player, or "".
+func (a *App) linkedDiscordID(ctx context.Context, name, uuid string) string {
+	e, err := a.WLStore.GetByUsername(ctx, name)
+	if err == nil && e == nil && uuid != "" {
+		e, err = a.WLStore.GetByUUID(ctx, uuid)
+	}
+	if err != nil {
+		logging.L().Warn("linkedDiscordID: lookup failed", "player", name, "error", err)
+		return ""
+	}
+	if e == nil {
+		return ""
+	}
+	return e.DiscordID
+}
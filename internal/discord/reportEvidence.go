@@ -0,0 +1,102 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/moderation"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+const (
+	evidenceChatLines    = 15
+	evidenceSessions     = 5
+	evidencePriorReports = 5
+)
+
+// postReportEvidence gathers what the bot knows about the reported player
+// and posts it in the report's thread, starting one on the report message
+// unless the report already is a forum post.
+func (a *App) postReportEvidence(r *reports.Report) {
+	if r.TargetPlayer == "" || r.MessageID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	embeds := []*discordgo.MessageEmbed{a.activityEmbed(ctx, r)}
+	target := &moderation.Punishment{Player: r.TargetPlayer, DiscordID: a.linkedDiscordID(ctx, r.TargetPlayer, r.TargetUUID)}
+	if e, err := a.infractionsEmbed(ctx, target); err != nil {
+		logging.L().Warn("postReportEvidence: infractions lookup failed", "report", r.ID, "error", err)
+	} else {
+		embeds = append(embeds, e)
+	}
+
+	threadID := r.ChannelID
+	if r.ChannelID != r.MessageID {
+		th, err := a.Session.MessageThreadStartComplex(r.ChannelID, r.MessageID, &discordgo.ThreadStart{
+			Name:                truncate(fmt.Sprintf("Report #%d – %s", r.ID, r.TargetPlayer), 100),
+			AutoArchiveDuration: threadArchiveMinutes,
+		})
+		if err != nil {
+			logging.L().Error("postReportEvidence: thread start failed", "report", r.ID, "error", err)
+			return
+		}
+		threadID = th.ID
+	}
+	if _, err := a.Session.ChannelMessageSendComplex(threadID, &discordgo.MessageSend{Embeds: embeds}); err != nil {
+		logging.L().Error("postReportEvidence: posting evidence failed", "report", r.ID, "error", err)
+	}
+}
+
+// activityEmbed lists the reported player's recent chat, sessions and
+// earlier reports.
+func (a *App) activityEmbed(ctx context.Context, r *reports.Report) *discordgo.MessageEmbed {
+	chat, sessions := a.Activity.Recent(r.TargetPlayer, evidenceChatLines, evidenceSessions)
+
+	lines := make([]string, 0, len(chat))
+	for _, c := range chat {
+		lines = append(lines, fmt.Sprintf("<t:%d:t> %s", c.At.Unix(), truncate(c.Text, 150)))
+	}
+	stays := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		switch {
+		case s.Joined.IsZero():
+			stays = append(stays, fmt.Sprintf("left <t:%d:f>", s.Left.Unix()))
+		case s.Left.IsZero():
+			stays = append(stays, fmt.Sprintf("joined <t:%d:f>, still online", s.Joined.Unix()))
+		default:
+			stays = append(stays, fmt.Sprintf("<t:%d:f> – <t:%d:t>", s.Joined.Unix(), s.Left.Unix()))
+		}
+	}
+
+	var prior []string
+	list, err := a.Reports.List(ctx, reports.Filter{Target: r.TargetPlayer, Limit: evidencePriorReports + 1})
+	if err != nil {
+		logging.L().Warn("activityEmbed: prior reports lookup failed", "report", r.ID, "error", err)
+	}
+	for _, p := range list {
+		if p.ID == r.ID || len(prior) == evidencePriorReports {
+			continue
+		}
+		line := fmt.Sprintf("#%d %s, <t:%d:d>", p.ID, p.Status, p.CreatedAt.Unix())
+		if p.MessageID != "" {
+			line += fmt.Sprintf(" [jump](%s)", messageLink(a.Cfg.GuildID, p.ChannelID, p.MessageID))
+		}
+		prior = append(prior, line)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Evidence: %s", r.TargetPlayer),
+		Color: 0x3B82F6,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Recent chat", Value: fieldList(lines)},
+			{Name: "Sessions", Value: fieldList(stays)},
+			{Name: "Earlier reports", Value: fieldList(prior)},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Chat and sessions only cover what the bot saw since it last started."},
+	}
+}
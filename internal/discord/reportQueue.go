@@ -0,0 +1,179 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+const reportsPageSize = 10
+
+func newReportsCommand(perm int64) *discordgo.ApplicationCommand {
+	minOne := float64(1)
+	id := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionInteger, Name: "id", Description: "Report ID", Required: true, MinValue: &minOne}
+	return &discordgo.ApplicationCommand{
+		Name:                     "reports",
+		Description:              "Staff report queue",
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "List reports",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type: discordgo.ApplicationCommandOptionString, Name: "status", Description: "Status (default open)", Required: false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "open", Value: reports.StatusOpen},
+							{Name: "resolved", Value: reports.StatusResolved},
+							{Name: "dismissed", Value: reports.StatusDismissed},
+							{Name: "all", Value: "all"},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "assignee", Description: "Only reports assigned to this member", Required: false},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "player", Description: "Only reports about this Minecraft player", Required: false, MaxLength: 64},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "page", Description: "Page number", Required: false, MinValue: &minOne},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "view", Description: "Show a report", Options: []*discordgo.ApplicationCommandOption{id}},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand, Name: "assign", Description: "Assign a report",
+				Options: []*discordgo.ApplicationCommandOption{
+					id,
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Staff member (defaults to you)", Required: false},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "reopen", Description: "Reopen a closed report", Options: []*discordgo.ApplicationCommandOption{id}},
+		},
+	}
+}
+
+func (a *App) handleReportsCommand(i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+	ctx := context.Background()
+
+	if sub.Name == "list" {
+		a.reportsList(ctx, i, opts)
+		return
+	}
+
+	r, err := a.Reports.Get(ctx, opts["id"].IntValue())
+	if err != nil {
+		logging.L().Error("reports: lookup failed", "id", opts["id"].IntValue(), "error", err)
+		a.reply(i, "Could not load the report, please try again later.", true)
+		return
+	}
+	if r == nil {
+		a.reply(i, fmt.Sprintf("Report #%d does not exist.", opts["id"].IntValue()), true)
+		return
+	}
+
+	switch sub.Name {
+	case "view":
+		e := reportEmbed(r)
+		if r.MessageID != "" {
+			e.URL = messageLink(a.Cfg.GuildID, r.ChannelID, r.MessageID)
+		}
+		_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{e}, Flags: discordgo.MessageFlagsEphemeral},
+		})
+	case "assign":
+		assignee := i.Member.User.ID
+		if o, ok := opts["user"]; ok {
+			assignee = o.UserValue(nil).ID
+		}
+		if err := a.Reports.Assign(ctx, r.ID, assignee); err != nil {
+			logging.L().Error("reports: assign failed", "report", r.ID, "error", err)
+			a.reply(i, "Could not assign the report, please try again.", true)
+			return
+		}
+		r.AssigneeID = assignee
+		a.refreshReportMessage(r)
+		a.reply(i, fmt.Sprintf("Report #%d assigned to <@%s>.", r.ID, assignee), true)
+	case "reopen":
+		if r.Status == reports.StatusOpen {
+			a.reply(i, fmt.Sprintf("Report #%d is already open.", r.ID), true)
+			return
+		}
+		if err := a.Reports.Reopen(ctx, r.ID); err != nil {
+			logging.L().Error("reports: reopen failed", "report", r.ID, "error", err)
+			a.reply(i, "Could not reopen the report, please try again.", true)
+			return
+		}
+		r.Status, r.ClosedBy = reports.StatusOpen, ""
+		a.refreshReportMessage(r)
+		logging.L().Info("report reopened", "report", r.ID, "moderator", i.Member.User.ID)
+		a.reply(i, fmt.Sprintf("Report #%d reopened.", r.ID), true)
+	}
+}
+
+func (a *App) reportsList(ctx context.Context, i *discordgo.InteractionCreate, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	f := reports.Filter{Status: reports.StatusOpen, Limit: reportsPageSize}
+	if o, ok := opts["status"]; ok {
+		f.Status = ternary(o.StringValue() == "all", "", o.StringValue())
+	}
+	if o, ok := opts["assignee"]; ok {
+		f.AssigneeID = o.UserValue(nil).ID
+	}
+	if o, ok := opts["player"]; ok {
+		f.Target = strings.TrimSpace(o.StringValue())
+	}
+	page := 1
+	if o, ok := opts["page"]; ok {
+		page = int(o.IntValue())
+	}
+	f.Offset = (page - 1) * reportsPageSize
+
+	list, err := a.Reports.List(ctx, f)
+	if err != nil {
+		logging.L().Error("reports: list failed", "error", err)
+		a.reply(i, "Could not load reports, please try again later.", true)
+		return
+	}
+
+	items := make([]string, 0, len(list))
+	for _, r := range list {
+		line := fmt.Sprintf("**#%d** %s · %s", r.ID, r.Status, strings.Title(r.Type))
+		if r.TargetPlayer != "" {
+			line += fmt.Sprintf(" `%s`", r.TargetPlayer)
+		}
+		line += fmt.Sprintf(" — by <@%s> <t:%d:R>", r.ReporterID, r.CreatedAt.Unix())
+		if r.AssigneeID != "" {
+			line += fmt.Sprintf(", assigned <@%s>", r.AssigneeID)
+		}
+		if r.MessageID != "" {
+			line += fmt.Sprintf(" [jump](%s)", messageLink(a.Cfg.GuildID, r.ChannelID, r.MessageID))
+		}
+		items = append(items, line)
+	}
+	desc := strings.Join(items, "\n")
+	if desc == "" {
+		desc = "No reports."
+	}
+
+	footer := fmt.Sprintf("Page %d", page)
+	if counts, err := a.Reports.CountByStatus(ctx); err == nil {
+		footer += fmt.Sprintf(" • %d open, %d resolved, %d dismissed",
+			counts[reports.StatusOpen], counts[reports.StatusResolved], counts[reports.StatusDismissed])
+	}
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:       "Reports",
+				Description: truncate(desc, 4096),
+				Color:       0xF44336,
+				Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+			}},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
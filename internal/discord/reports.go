@@ -1,142 +1,560 @@
 package discord
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
 )
 
-func (a *App) openReportModal(i *discordgo.InteractionCreate) {
-	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseModal,
+// openReportCategories starts a report with the category picker. With a
+// single category its modal opens directly.
+func (a *App) openReportCategories(i *discordgo.InteractionCreate) {
+	cats := a.ReportCategories.Current()
+	if len(cats.Categories) == 1 {
+		a.openReportModal(i, &cats.Categories[0])
+		return
+	}
+	options := make([]discordgo.SelectMenuOption, 0, len(cats.Categories))
+	for _, c := range cats.Categories {
+		opt := discordgo.SelectMenuOption{Label: truncate(c.Label, 100), Value: c.ID, Description: truncate(c.Description, 100)}
+		if c.Emoji != "" {
+			opt.Emoji = &discordgo.ComponentEmoji{Name: c.Emoji}
+		}
+		options = append(options, opt)
+	}
+	err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			CustomID: "report_modal",
-			Title:    "Report Issue",
+			Content: "What would you like to report?",
+			Flags:   discordgo.MessageFlagsEphemeral,
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					&discordgo.TextInput{CustomID: "report_type", Label: "Type (player / bug / other)", Style: discordgo.TextInputShort, Required: true, MaxLength: 16},
-				}},
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					&discordgo.TextInput{CustomID: "reported_username", Label: "Player (if type=player)", Style: discordgo.TextInputShort, Required: false, MaxLength: 64},
-				}},
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					&discordgo.TextInput{CustomID: "report_reason", Label: "Details", Style: discordgo.TextInputParagraph, Required: true, MaxLength: 1000},
-				}},
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					&discordgo.TextInput{CustomID: "report_evidence", Label: "Evidence (links)", Style: discordgo.TextInputShort, Required: false, MaxLength: 200},
-				}},
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					&discordgo.TextInput{CustomID: "report_context", Label: "Context (optional)", Style: discordgo.TextInputShort, Required: false, MaxLength: 200},
+					discordgo.SelectMenu{CustomID: "report_category", Placeholder: "Choose a category", Options: options},
 				}},
 			},
 		},
 	})
+	if err != nil {
+		logging.L().Error("openReportCategories: respond failed", "error", err)
+	}
+}
+
+func (a *App) handleReportCategorySelect(i *discordgo.InteractionCreate) {
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return
+	}
+	cat := a.ReportCategories.Current().Get(values[0])
+	if cat == nil {
+		a.reply(i, "This category no longer exists, please run /report again.", true)
+		return
+	}
+	a.openReportModal(i, cat)
+}
+
+func (a *App) openReportModal(i *discordgo.InteractionCreate, cat *reports.Category) {
+	rows := make([]discordgo.MessageComponent, 0, len(cat.Fields))
+	for _, f := range cat.Fields {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.TextInput{
+				CustomID:    f.ID,
+				Label:       truncate(f.Label, 45),
+				Style:       ternary(f.Style == "paragraph", discordgo.TextInputParagraph, discordgo.TextInputShort),
+				Placeholder: f.Placeholder,
+				Required:    f.Required,
+				MaxLength:   ternary(f.MaxLength > 0, f.MaxLength, 1000),
+			},
+		}})
+	}
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   components.ID("report_modal", a.putComponentState(context.Background(), reportModalState, reportModalPayload{Category: cat.ID})),
+			Title:      truncate(cat.Label+" Report", 45),
+			Components: rows,
+		},
+	})
 }
 
 func (a *App) handleReportSubmit(i *discordgo.InteractionCreate) {
-	t := strings.ToLower(strings.TrimSpace(modalValue(i, "report_type")))
-	player := strings.TrimSpace(modalValue(i, "reported_username"))
-	reason := strings.TrimSpace(modalValue(i, "report_reason"))
-	evidence := strings.TrimSpace(modalValue(i, "report_evidence"))
-	context := strings.TrimSpace(modalValue(i, "report_context"))
+	var st reportModalPayload
+	if err := a.componentState(context.Background(), i.ModalSubmitData().CustomID, reportModalState, &st); err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	cat := a.ReportCategories.Current().Get(st.Category)
+	if cat == nil {
+		a.reply(i, "This category no longer exists, please run /report again.", true)
+		return
+	}
+	r := &reports.Report{ReporterID: i.Member.User.ID, Type: cat.ID}
+	for _, f := range cat.Fields {
+		v := strings.TrimSpace(modalValue(i, f.ID))
+		switch f.ID {
+		case reports.FieldPlayer:
+			r.TargetPlayer = v
+		case reports.FieldDetails:
+			r.Details = v
+		case reports.FieldEvidence:
+			r.Evidence = v
+		case reports.FieldContext:
+			r.Context = v
+		default:
+			if v != "" {
+				r.Extra = append(r.Extra, reports.Answer{Label: f.Label, Value: v})
+			}
+		}
+	}
 
-	if t == "player" && player == "" {
-		a.reply(i, "Player report requires a username.", true)
+	if channelID, _ := a.reportDestination(r.Type); channelID == "" {
+		// Channel unset: log and inform user.
+		logging.L().Warn("handleReportSubmit: no report channel configured; report not delivered",
+			"type", r.Type,
+			"reporter_discord_id", r.ReporterID,
+			"reported_player", r.TargetPlayer,
+		)
+		a.reply(i, "Report could not be delivered at the moment. Please contact staff members if this issue persists.", true)
 		return
 	}
 
+	// Resolving the reported player's UUID can outlive the 3s interaction window.
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		logging.L().Error("handleReportSubmit: defer failed", "error", err)
+		return
+	}
+
+	ctx := context.Background()
+	if r.TargetPlayer != "" {
+		r.TargetUUID = a.playerUUID(ctx, r.TargetPlayer)
+	}
+	a.fileReport(ctx, i, r)
+}
+
+// reportDestination returns the channel reports of the given type go to and
+// their category, which is nil for types without one.
+func (a *App) reportDestination(typ string) (string, *reports.Category) {
+	cat := a.ReportCategories.Current().Get(typ)
+	if cat != nil && cat.ChannelID != "" {
+		return cat.ChannelID, cat
+	}
+	return a.Cfg.ReportChannelID, cat
+}
+
+// fileReport posts r and answers the deferred interaction with the report ID.
+func (a *App) fileReport(ctx context.Context, i *discordgo.InteractionCreate, r *reports.Report) {
+	out := "Report could not be delivered at the moment. Please contact staff members if this issue persists."
+	if err := a.postReport(ctx, r); err == nil {
+		out = fmt.Sprintf("Report #%d submitted.", r.ID)
+	}
+	_, _ = a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &out})
+}
+
+// postReport stores r and posts it to the report channel.
+func (a *App) postReport(ctx context.Context, r *reports.Report) error {
+	if err := a.Reports.Create(ctx, r); err != nil {
+		logging.L().Error("postReport: saving report failed", "reporter", r.ReporterID, "error", err)
+		return err
+	}
+	channelID, cat := a.reportDestination(r.Type)
+	send := &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{reportEmbed(r)},
+		Components: a.reportComponents(r),
+	}
+	ch, err := a.Session.State.Channel(channelID)
+	if err != nil {
+		ch, err = a.Session.Channel(channelID)
+	}
+	if err == nil && ch.Type == discordgo.ChannelTypeGuildForum {
+		// A forum post's starter message shares the thread's ID.
+		var th *discordgo.Channel
+		th, err = a.Session.ForumThreadStartComplex(channelID, &discordgo.ThreadStart{
+			Name:        reportThreadName(r),
+			AppliedTags: forumTagIDs(ch, cat),
+		}, send)
+		if err == nil {
+			r.ChannelID, r.MessageID = th.ID, th.ID
+		}
+	} else {
+		var msg *discordgo.Message
+		msg, err = a.Session.ChannelMessageSendComplex(channelID, send)
+		if err == nil {
+			r.ChannelID, r.MessageID = msg.ChannelID, msg.ID
+		}
+	}
+	if err != nil {
+		logging.L().Error("postReport: posting report failed", "report", r.ID, "channel", channelID, "error", err)
+		return err
+	}
+	if err := a.Reports.SetMessage(ctx, r.ID, r.ChannelID, r.MessageID); err != nil {
+		logging.L().Error("postReport: saving message failed", "report", r.ID, "error", err)
+	}
+	if r.TargetPlayer != "" {
+		cp := *r
+		go a.postReportEvidence(&cp)
+	}
+	return nil
+}
+
+// reportThreadName titles the forum post of r, e.g. "#12 Hopper duplicates items".
+func reportThreadName(r *reports.Report) string {
+	title := strings.TrimSpace(strings.SplitN(r.Details, "\n", 2)[0])
+	if title == "" {
+		title = strings.Title(r.Type) + " report"
+	}
+	return truncate(fmt.Sprintf("#%d %s", r.ID, title), 100)
+}
+
+// forumTagIDs maps the category's tag names to the forum's tag IDs.
+func forumTagIDs(forum *discordgo.Channel, cat *reports.Category) []string {
+	if cat == nil {
+		return nil
+	}
+	var ids []string
+	for _, name := range cat.ForumTags {
+		found := false
+		for _, t := range forum.AvailableTags {
+			if strings.EqualFold(t.Name, name) {
+				ids = append(ids, t.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			logging.L().Warn("forumTagIDs: forum has no such tag", "forum", forum.ID, "tag", name)
+		}
+	}
+	return ids
+}
+
+// playerUUID resolves a reported player's UUID, preferring the whitelist so
+// offline lookups still work. It returns "" when the name is unknown.
+func (a *App) playerUUID(ctx context.Context, name string) string {
+	if e, err := a.WLStore.GetByUsername(ctx, name); err == nil && e != nil {
+		return e.MinecraftUUID
+	}
+	uuid, err := a.NameMC.UsernameToUUID(name)
+	if err != nil {
+		logging.L().Debug("playerUUID: resolve failed", "name", name, "error", err)
+		return ""
+	}
+	return whitelist.NormalizeUUID(uuid)
+}
+
+// reportEmbed renders a stored report for the staff channel.
+func reportEmbed(r *reports.Report) *discordgo.MessageEmbed {
+	reporter := "<@" + r.ReporterID + ">"
+	switch {
+	case r.ReporterID == "":
+		reporter = "`" + r.ReporterName + "` (not linked)"
+	case r.ReporterName != "":
+		reporter += " (`" + r.ReporterName + "`)"
+	}
 	fields := []*discordgo.MessageEmbedField{
-		{Name: "Reporter", Value: "<@" + i.Member.User.ID + ">", Inline: true},
-		{Name: "Type", Value: strings.Title(t), Inline: true},
+		{Name: "Reporter", Value: reporter, Inline: true},
+		{Name: "Type", Value: strings.Title(r.Type), Inline: true},
 	}
-	if player != "" {
-		fields = append(fields, &discordgo.MessageEmbedField{Name: "Reported Player", Value: "`" + player + "`", Inline: true})
+	if r.TargetPlayer != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Reported Player", Value: "`" + truncate(r.TargetPlayer, 64) + "`", Inline: true})
 	}
-	if reason != "" {
-		fields = append(fields, &discordgo.MessageEmbedField{Name: "Details", Value: reason})
+	// Answers are stored in full but an embed field holds at most 1024
+	// characters, and one oversized field fails the whole message.
+	if r.Details != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Details", Value: truncate(r.Details, 1024)})
 	}
-	if evidence != "" {
-		fields = append(fields, &discordgo.MessageEmbedField{Name: "Evidence", Value: evidence})
+	if r.Evidence != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Evidence", Value: truncate(r.Evidence, 1024)})
 	}
-	if context != "" {
-		fields = append(fields, &discordgo.MessageEmbedField{Name: "Context", Value: context})
+	if r.Context != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Context", Value: truncate(r.Context, 1024)})
+	}
+	for _, e := range r.Extra {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: truncate(e.Label, 256), Value: truncate(e.Value, 1024)})
+	}
+	if r.Location != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Location", Value: truncate(r.Location, 1024), Inline: true})
+	}
+	if r.AssigneeID != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Assignee", Value: "<@" + r.AssigneeID + ">", Inline: true})
+	}
+
+	desc := ternary(r.Source == reports.SourceMinecraft, "A new report has been filed in-game.", "A new report has been filed.")
+	color := 0xF44336
+	switch r.Status {
+	case reports.StatusResolved, reports.StatusDismissed:
+		label := ternary(r.Status == reports.StatusResolved, "Resolved", "Dismissed")
+		color = ternary(r.Status == reports.StatusResolved, 0x22C55E, 0xEF4444)
+		note := ternary(r.Notes != "", r.Notes, "(no note)")
+		desc += fmt.Sprintf("\n\n📝 %s by <@%s>. Note: %s", label, r.ClosedBy, note)
+		if r.PublicNote != "" {
+			desc += "\n✉️ Message to reporter: " + r.PublicNote
+		}
 	}
 
-	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("New %s Report", strings.Title(t)),
-		Description: "A new report has been filed.",
-		Color:       0xF44336,
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("New %s Report", strings.Title(r.Type)),
+		Description: desc,
+		Color:       color,
 		Fields:      fields,
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		Footer:      &discordgo.MessageEmbedFooter{Text: "Rotaria Moderation"},
+		Timestamp:   r.UpdatedAt.UTC().Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Rotaria Moderation • Report #%d", r.ID)},
 	}
+}
 
-	components := []discordgo.MessageComponent{
+// reportComponents returns the staff buttons of an open report. They share
+// one state key holding the report ID, which is dropped once the report is
+// closed.
+func (a *App) reportComponents(r *reports.Report) []discordgo.MessageComponent {
+	ctx := context.Background()
+	owner := strconv.FormatInt(r.ID, 10)
+	if r.Status != reports.StatusOpen {
+		if err := a.Components.Drop(ctx, reportState, owner); err != nil {
+			logging.L().Warn("reportComponents: dropping state failed", "report", r.ID, "error", err)
+		}
+		return []discordgo.MessageComponent{}
+	}
+	key := a.keepComponentState(ctx, reportState, owner, reportPayload{ReportID: r.ID, ReporterID: r.ReporterID})
+	rows := []discordgo.MessageComponent{
 		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-			&discordgo.Button{CustomID: "report_resolve_" + player + "|" + i.Member.User.ID, Label: "Resolve", Style: discordgo.SuccessButton},
-			&discordgo.Button{CustomID: "report_dismiss_" + player + "|" + i.Member.User.ID, Label: "Dismiss", Style: discordgo.DangerButton},
+			&discordgo.Button{CustomID: components.ID("report_resolve", key), Label: "Resolve", Style: discordgo.SuccessButton},
+			&discordgo.Button{CustomID: components.ID("report_dismiss", key), Label: "Dismiss", Style: discordgo.DangerButton},
+			&discordgo.Button{CustomID: "report_claim", Label: "Claim", Style: discordgo.SecondaryButton},
 		}},
 	}
+	if r.TargetPlayer != "" {
+		rows = append(rows, reportModerationRow(key))
+	}
+	return rows
+}
+
+// refreshReportMessage re-renders the staff message of r after a change
+// made outside of it, e.g. through /reports.
+func (a *App) refreshReportMessage(r *reports.Report) {
+	if r.MessageID == "" {
+		return
+	}
+	embeds := []*discordgo.MessageEmbed{reportEmbed(r)}
+	buttons := a.reportComponents(r)
+	if _, err := a.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    r.ChannelID,
+		ID:         r.MessageID,
+		Embeds:     &embeds,
+		Components: &buttons,
+	}); err != nil {
+		logging.L().Warn("refreshReportMessage: edit failed", "report", r.ID, "error", err)
+	}
+}
+
+// notifyReporter DMs the reporter the outcome of r unless they opted out. It
+// returns a short suffix for the moderator's confirmation.
+func (a *App) notifyReporter(ctx context.Context, r *reports.Report) string {
+	if r.ReporterID == "" {
+		return ""
+	}
+	enabled, err := a.Reports.NotifyEnabled(ctx, r.ReporterID)
+	if err != nil {
+		logging.L().Warn("notifyReporter: opt-out lookup failed", "reporter", r.ReporterID, "error", err)
+		return ""
+	}
+	if !enabled {
+		return " The reporter opted out of updates."
+	}
+
+	subject := "Your " + ternary(r.Type != "", r.Type+" ", "") + "report"
+	if r.ID > 0 {
+		subject += fmt.Sprintf(" #%d", r.ID)
+	}
+	if r.TargetPlayer != "" {
+		subject += fmt.Sprintf(" about `%s`", r.TargetPlayer)
+	}
+	msg := fmt.Sprintf("%s has been **%s** by staff. Thank you for reporting!", subject, r.Status)
+	if r.PublicNote != "" {
+		msg += "\n> " + strings.ReplaceAll(r.PublicNote, "\n", "\n> ")
+	}
 
-	if a.Cfg.ReportChannelID != "" {
-		_, _ = a.Session.ChannelMessageSendComplex(a.Cfg.ReportChannelID, &discordgo.MessageSend{
-			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: components,
+	dm, err := a.Session.UserChannelCreate(r.ReporterID)
+	if err == nil {
+		_, err = a.Session.ChannelMessageSendComplex(dm.ID, &discordgo.MessageSend{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{CustomID: "report_optout", Label: "Stop report updates", Style: discordgo.SecondaryButton},
+				}},
+			},
 		})
-		// Success case: keep existing user reply below.
-	} else {
-		// Channel unset: log and inform user.
-		logging.L().Warn("handleReportSubmit: ReportChannelID not configured; report not delivered",
-			"type", t,
-			"reporter_discord_id", i.Member.User.ID,
-			"reported_player", player,
-		)
-		a.reply(i, "Report could not be delivered at the moment. Please contact staff members if this issue persists.", true)
+	}
+	if err != nil {
+		logging.L().Warn("notifyReporter: DM failed", "reporter", r.ReporterID, "error", err)
+		return " The reporter could not be DMed."
+	}
+	return " The reporter was notified."
+}
+
+func newReportUpdatesCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "reportupdates",
+		Description: "Choose whether you get a DM when your reports are closed",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "enabled", Description: "Send me report updates", Required: true},
+		},
+	}
+}
+
+func (a *App) handleReportUpdatesCommand(i *discordgo.InteractionCreate) {
+	a.setReportNotify(i, i.ApplicationCommandData().Options[0].BoolValue())
+}
+
+// handleReportOptOut is the button under a report update DM.
+func (a *App) handleReportOptOut(i *discordgo.InteractionCreate) {
+	a.setReportNotify(i, false)
+}
+
+func (a *App) setReportNotify(i *discordgo.InteractionCreate, enabled bool) {
+	userID := interactionUserID(i)
+	if err := a.Reports.SetNotify(context.Background(), userID, enabled); err != nil {
+		logging.L().Error("setReportNotify: failed", "user", userID, "error", err)
+		a.reply(i, "Could not save your preference, please try again later.", true)
 		return
 	}
+	a.reply(i, ternary(enabled,
+		"✅ You will get a DM when your reports are resolved or dismissed.",
+		"🔕 You will no longer get DMs about your reports. Use /reportupdates to turn them back on."), true)
+}
 
-	a.reply(i, "Report submitted.", true)
+// handleReportClaim assigns the report to the staff member who clicked Claim.
+// reportStaff reports whether the member may work on reports: claim,
+// resolve, dismiss or punish. Anyone else is told so.
+func (a *App) reportStaff(i *discordgo.InteractionCreate) bool {
+	if hasPermission(i, discordgo.PermissionBanMembers) {
+		return true
+	}
+	a.reply(i, "You need the Ban Members permission for this.", true)
+	return false
+}
+
+func (a *App) handleReportClaim(i *discordgo.InteractionCreate) {
+	if !a.reportStaff(i) {
+		return
+	}
+	ctx := context.Background()
+	r, err := a.Reports.GetByMessage(ctx, i.Message.ID)
+	if err != nil || r == nil {
+		a.reply(i, "This report is not tracked.", true)
+		return
+	}
+	if err := a.Reports.Assign(ctx, r.ID, i.Member.User.ID); err != nil {
+		logging.L().Error("handleReportClaim: assign failed", "report", r.ID, "error", err)
+		a.reply(i, "Could not claim the report, please try again.", true)
+		return
+	}
+	r.AssigneeID = i.Member.User.ID
+	r.UpdatedAt = time.Now()
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{reportEmbed(r)},
+			Components: a.reportComponents(r),
+		},
+	})
 }
 
 func (a *App) openReportActionModal(i *discordgo.InteractionCreate) {
+	if !a.reportStaff(i) {
+		return
+	}
 	cid := i.MessageComponentData().CustomID
 	action := "resolve"
-	if strings.HasPrefix(cid, "report_dismiss_") {
+	if componentAction(cid) == "report_dismiss" || strings.HasPrefix(cid, "report_dismiss_") {
 		action = "dismiss"
 	}
+	_, key, ok := components.Split(cid)
+	if !ok {
+		// Buttons posted before component state end in "|<reporterID>".
+		key = a.putComponentState(context.Background(), reportState, reportPayload{ReporterID: cid[strings.LastIndex(cid, "|")+1:]})
+	}
 	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
-			CustomID: "report_action_modal|" + action + "|" + cid,
+			CustomID: components.ID("report_"+action+"_modal", key),
 			Title:    strings.Title(action) + " Report",
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
 					&discordgo.TextInput{CustomID: "moderator_note", Label: "Moderator Note", Style: discordgo.TextInputParagraph, Required: true, MaxLength: 1000},
 				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: "public_note", Label: "Message to reporter (optional)", Style: discordgo.TextInputParagraph, Required: false, MaxLength: 500},
+				}},
 			},
 		},
 	})
 }
 
 func (a *App) handleReportActionModal(i *discordgo.InteractionCreate) {
-	parts := strings.SplitN(i.ModalSubmitData().CustomID, "|", 3)
-	if len(parts) != 3 {
+	if !a.reportStaff(i) {
 		return
 	}
-	action := parts[1]
-	orig := parts[2]
+	cid := i.ModalSubmitData().CustomID
+	var st reportPayload
+	if err := a.componentState(context.Background(), cid, reportState, &st); err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	action := ternary(componentAction(cid) == "report_dismiss_modal", "dismiss", "resolve")
 	note := modalValue(i, "moderator_note")
 	if note == "" {
 		note = "(no note)"
 	}
+	publicNote := modalValue(i, "public_note")
 	msg := i.Message
 	if msg == nil || len(msg.Embeds) == 0 {
 		a.reply(i, "Original report message missing.", true)
 		return
 	}
+	ctx := context.Background()
+	var (
+		r   *reports.Report
+		err error
+	)
+	if st.ReportID > 0 {
+		r, err = a.Reports.Get(ctx, st.ReportID)
+	} else {
+		r, err = a.Reports.GetByMessage(ctx, msg.ID)
+	}
+	if err != nil {
+		logging.L().Error("handleReportActionModal: lookup failed", "message", msg.ID, "error", err)
+	}
+	if r != nil {
+		if r.Status != reports.StatusOpen {
+			a.reply(i, fmt.Sprintf("Report #%d was already %s.", r.ID, r.Status), true)
+			return
+		}
+		status := ternary(action == "dismiss", reports.StatusDismissed, reports.StatusResolved)
+		if err := a.Reports.Close(ctx, r.ID, status, i.Member.User.ID, note, publicNote); errors.Is(err, reports.ErrClosed) {
+			a.reply(i, fmt.Sprintf("Report #%d was already closed.", r.ID), true)
+			return
+		} else if err != nil {
+			logging.L().Error("handleReportActionModal: saving failed", "report", r.ID, "error", err)
+			a.reply(i, "Could not update the report, please try again.", true)
+			return
+		}
+		r.Status, r.ClosedBy, r.Notes, r.PublicNote, r.UpdatedAt = status, i.Member.User.ID, note, publicNote, time.Now()
+		a.refreshReportMessage(r)
+		a.reply(i, fmt.Sprintf("Report #%d updated.%s", r.ID, a.notifyReporter(ctx, r)), true)
+		return
+	}
+
+	// Reports posted before they were stored only have their embed.
 	cp := *msg.Embeds[0]
 	label := "Resolved"
 	color := 0x22C55E
@@ -161,6 +579,11 @@ func (a *App) handleReportActionModal(i *discordgo.InteractionCreate) {
 		Embeds:     &embeds,
 		Components: &components,
 	})
-	a.reply(i, "Report updated.", true)
-	_ = orig
+	legacy := &reports.Report{
+		ReporterID: st.ReporterID,
+		Type:       strings.ToLower(embedFieldValue(&cp, "Type")),
+		Status:     ternary(action == "dismiss", reports.StatusDismissed, reports.StatusResolved),
+		PublicNote: publicNote,
+	}
+	a.reply(i, "Report updated."+a.notifyReporter(ctx, legacy), true)
 }
@@ -0,0 +1,32 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/reports"
+)
+
+func TestReportEmbedFitsLongAnswers(t *testing.T) {
+	long := strings.Repeat("a", 4000)
+	r := &reports.Report{
+		ID:         1,
+		ReporterID: "1",
+		Type:       "bug",
+		Status:     reports.StatusOpen,
+		Details:    long,
+		Evidence:   long,
+		Context:    long,
+		Extra:      []reports.Answer{{Label: "Steps", Value: long}},
+	}
+	embed := reportEmbed(r)
+	for _, f := range embed.Fields {
+		if n := utf8.RuneCountInString(f.Value); n > 1024 {
+			t.Errorf("field %q has %d characters, want at most 1024", f.Name, n)
+		}
+	}
+	if embed.Fields[2].Name != "Details" || !strings.HasSuffix(embed.Fields[2].Value, "…") {
+		t.Errorf("Details field = %q…, want the answer cut short", embed.Fields[2].Value[:10])
+	}
+}
@@ -0,0 +1,82 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/policy"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// screenApplication gathers the facts the policy rules need and evaluates
+// them. Lookups that fail leave the fact unknown, which never matches.
+func (a *App) screenApplication(ctx context.Context, i *discordgo.InteractionCreate, app *applications.Application) []policy.Hit {
+	in := policy.Input{Now: time.Now()}
+
+	if age, err := strconv.Atoi(strings.TrimSpace(app.Answer(a.Policy.AgeQuestion))); err == nil {
+		in.Age = age
+	}
+	if created, err := discordgo.SnowflakeTimestamp(app.DiscordID); err == nil {
+		in.AccountCreated = created
+	}
+	if i.Member != nil {
+		in.JoinedGuild = i.Member.JoinedAt
+	}
+	in.UsernameBlocked = a.Blacklist != nil && a.Blacklist.Contains(app.MinecraftName)
+
+	if entry, err := a.WLStore.GetByUUID(ctx, app.MinecraftUUID); err != nil {
+		logging.L().Warn("screenApplication: whitelist lookup failed", "uuid", app.MinecraftUUID, "error", err)
+	} else if entry != nil {
+		in.WhitelistedBy = entry.DiscordID
+	}
+	// /whitelist already refuses users on cooldown; this catches forms opened before the rejection.
+	if until, err := a.Applications.Cooldown(ctx, app.DiscordID); err != nil {
+		logging.L().Warn("screenApplication: cooldown lookup failed", "discord_id", app.DiscordID, "error", err)
+	} else {
+		in.CooldownUntil = until
+	}
+
+	hits := a.Policy.Evaluate(app.DiscordID, in)
+	if len(hits) > 0 {
+		rules := make([]string, 0, len(hits))
+		for _, h := range hits {
+			rules = append(rules, h.Rule+"="+string(h.Action))
+		}
+		logging.L().Info("screenApplication: policy hits", "discord_id", app.DiscordID, "username", app.MinecraftName, "rules", strings.Join(rules, ","))
+	}
+	return hits
+}
+
+// autoReject closes an application that broke a rejecting rule and tells the
+// applicant why.
+func (a *App) autoReject(ctx context.Context, i *discordgo.InteractionCreate, app *applications.Application, hits []policy.Hit) {
+	if err := a.Applications.SetStatus(ctx, app.ID, applications.StatusRejected, applications.DecidedByPolicy); err != nil {
+		logging.L().Error("autoReject: status update failed", "application", app.ID, "error", err)
+	}
+
+	var reasons []string
+	for _, h := range hits {
+		if h.Action == policy.Reject {
+			reasons = append(reasons, h.Reason)
+		}
+	}
+	msg := fmt.Sprintf("❌ Your whitelist application for `%s` was not accepted:\n• %s", app.MinecraftName, strings.Join(reasons, "\n• "))
+
+	if dm, err := a.Session.UserChannelCreate(app.DiscordID); err == nil {
+		_, _ = a.Session.ChannelMessageSend(dm.ID, msg)
+	}
+	a.reply(i, msg, true)
+}
+
+func hitList(hits []policy.Hit) string {
+	items := make([]string, 0, len(hits))
+	for _, h := range hits {
+		items = append(items, fmt.Sprintf("`%s` %s", h.Rule, h.Reason))
+	}
+	return fieldList(items)
+}
@@ -0,0 +1,211 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/panels"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+func newSetupCommand(perm int64) *discordgo.ApplicationCommand {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, k := range panels.Kinds {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: k, Value: k})
+	}
+	return &discordgo.ApplicationCommand{
+		Name:                     "setup",
+		Description:              "Post and manage persistent panels",
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "post",
+				Description: "Post a panel, moving it if it already exists elsewhere",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "panel", Description: "Panel to post", Required: true, Choices: choices},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Channel to post in",
+						Required:     true,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+					},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "refresh", Description: "Update every posted panel from the current config"},
+		},
+	}
+}
+
+func (a *App) handleSetupCommand(i *discordgo.InteractionCreate) {
+	s := a.Session
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := make([]byte, 8192)
+				n := runtime.Stack(stack, false)
+				logging.L().Error("setup panic", "recover", r, "stack", string(stack[:n]))
+				safe := "internal error during setup"
+				_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &safe})
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		sub := i.ApplicationCommandData().Options[0]
+		var msg string
+		switch sub.Name {
+		case "post":
+			kind := sub.Options[0].StringValue()
+			channel := sub.Options[1].ChannelValue(nil)
+			if err := a.postPanel(ctx, kind, channel.ID); err != nil {
+				logging.L().Error("setup: posting panel failed", "panel", kind, "channel", channel.ID, "error", err)
+				msg = fmt.Sprintf("Could not post the %s panel: %v", kind, err)
+			} else {
+				logging.L().Info("setup: panel posted", "panel", kind, "channel", channel.ID, "moderator", i.Member.User.ID)
+				msg = fmt.Sprintf("✅ Posted the %s panel in <#%s>.", kind, channel.ID)
+			}
+		case "refresh":
+			msg = a.refreshPanels(ctx)
+		}
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+	}()
+}
+
+// postPanel posts the panel in channelID. An existing panel in the same
+// channel is edited in place; one in another channel is deleted first.
+func (a *App) postPanel(ctx context.Context, kind, channelID string) error {
+	content, err := a.panelMessage(kind)
+	if err != nil {
+		return err
+	}
+	old, err := a.Panels.Get(ctx, kind)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		if old.ChannelID == channelID {
+			if err := a.editPanel(old, content); err == nil {
+				return a.Panels.Save(ctx, *old)
+			} else if !isNotFound(err) {
+				return err
+			}
+		} else if err := a.Session.ChannelMessageDelete(old.ChannelID, old.MessageID); err != nil && !isNotFound(err) {
+			logging.L().Warn("postPanel: deleting old panel failed", "panel", kind, "error", err)
+		}
+	}
+
+	m, err := a.Session.ChannelMessageSendComplex(channelID, content)
+	if err != nil {
+		return err
+	}
+	return a.Panels.Save(ctx, panels.Panel{Kind: kind, ChannelID: channelID, MessageID: m.ID})
+}
+
+// refreshPanels re-renders every stored panel and reports the outcome.
+// Panels whose message was deleted are posted again in the same channel.
+func (a *App) refreshPanels(ctx context.Context) string {
+	list, err := a.Panels.List(ctx)
+	if err != nil {
+		return fmt.Sprintf("Could not load panels: %v", err)
+	}
+	if len(list) == 0 {
+		return "No panels have been posted yet. Use `/setup post` first."
+	}
+	var lines []string
+	for _, p := range list {
+		if err := a.postPanel(ctx, p.Kind, p.ChannelID); err != nil {
+			logging.L().Error("refreshPanels: failed", "panel", p.Kind, "error", err)
+			lines = append(lines, fmt.Sprintf("❌ %s in <#%s>: %v", p.Kind, p.ChannelID, err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("✅ %s in <#%s>", p.Kind, p.ChannelID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (a *App) editPanel(p *panels.Panel, content *discordgo.MessageSend) error {
+	_, err := a.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    p.ChannelID,
+		ID:         p.MessageID,
+		Content:    &content.Content,
+		Embeds:     &content.Embeds,
+		Components: &content.Components,
+	})
+	return err
+}
+
+// panelMessage builds the current content of a panel.
+func (a *App) panelMessage(kind string) (*discordgo.MessageSend, error) {
+	switch kind {
+	case panels.Whitelist:
+		form := a.Forms.Current()
+		desc := "Want to play on Rotaria? Press the button below to fill in the application form. Staff will review it and you will get a DM with the outcome."
+		if a.Cfg.PolicyReapplyCooldown > 0 {
+			desc += fmt.Sprintf("\n\nRejected applicants can apply again after %s.", a.Cfg.PolicyReapplyCooldown)
+		}
+		return panelSend(form.Title, desc, 0x3B82F6, discordgo.Button{
+			CustomID: "request_whitelist", Label: "Apply", Style: discordgo.SuccessButton,
+		}), nil
+	case panels.Report:
+		return panelSend("Report a problem",
+			"Seen griefing, cheating or a bug? Press the button below to send a report to staff. Only staff can see your report.",
+			0xF59E0B, discordgo.Button{
+				CustomID: "request_report", Label: "Report", Style: discordgo.DangerButton,
+			}), nil
+	case panels.Rules:
+		raw, err := os.ReadFile(a.Cfg.RulesPath)
+		if err != nil {
+			return nil, fmt.Errorf("read rules: %w", err)
+		}
+		return panelSend("Server rules", truncate(strings.TrimSpace(string(raw)), 4096), 0x22C55E, discordgo.Button{
+			CustomID: "rules_accept", Label: "I accept the rules", Style: discordgo.SuccessButton,
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown panel %q", kind)
+}
+
+func panelSend(title, desc string, color int, button discordgo.Button) *discordgo.MessageSend {
+	return &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{{Title: title, Description: desc, Color: color}},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{button}},
+		},
+	}
+}
+
+// handleRulesAccept gives the member RulesRoleID when they accept the rules.
+func (a *App) handleRulesAccept(i *discordgo.InteractionCreate) {
+	if a.Cfg.RulesRoleID == "" {
+		a.reply(i, "Thanks for reading the rules!", true)
+		return
+	}
+	userID := interactionUserID(i)
+	if err := a.Session.GuildMemberRoleAdd(i.GuildID, userID, a.Cfg.RulesRoleID); err != nil {
+		logging.L().Error("handleRulesAccept: role add failed", "user", userID, "error", err)
+		a.reply(i, "Could not give you the role, please try again later.", true)
+		return
+	}
+	a.reply(i, "✅ Thanks for accepting the rules!", true)
+}
+
+func isNotFound(err error) bool {
+	var rest *discordgo.RESTError
+	return errors.As(err, &rest) && rest.Response != nil && rest.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,126 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// checkStaleApplications reminds staff about applications pending longer
+// than ApplicationRemindAfter and expires those older than
+// ApplicationExpireAfter.
+func (a *App) checkStaleApplications(ctx context.Context) {
+	pending, err := a.Applications.ListPending(ctx)
+	if err != nil {
+		logging.L().Error("checkStaleApplications: list failed", "error", err)
+		return
+	}
+
+	now := time.Now()
+	var remind []*applications.Application
+	for _, app := range pending {
+		age := now.Sub(app.CreatedAt)
+		switch {
+		case a.Cfg.ApplicationExpireAfter > 0 && age >= a.Cfg.ApplicationExpireAfter:
+			a.expireApplication(ctx, app)
+		case a.Cfg.ApplicationRemindAfter > 0 && age >= a.Cfg.ApplicationRemindAfter && app.RemindedAt.IsZero():
+			remind = append(remind, app)
+		}
+	}
+	if len(remind) > 0 {
+		a.remindStaff(ctx, remind)
+	}
+}
+
+// remindStaff posts one message listing the given applications and marks
+// them reminded so each is only pinged once.
+func (a *App) remindStaff(ctx context.Context, apps []*applications.Application) {
+	channelID := ternary(a.Cfg.StaffChannelID != "", a.Cfg.StaffChannelID, a.Cfg.WhitelistRequestsChannelID)
+	if channelID == "" {
+		return
+	}
+
+	items := make([]string, 0, len(apps))
+	for _, app := range apps {
+		items = append(items, fmt.Sprintf("#%d `%s` by <@%s>, waiting since <t:%d:R> — %s",
+			app.ID, app.MinecraftName, app.DiscordID, app.CreatedAt.Unix(), messageLink(a.Cfg.GuildID, app.ChannelID, app.MessageID)))
+	}
+	var content string
+	mentions := &discordgo.MessageAllowedMentions{}
+	if a.Cfg.ApplicationReminderRoleID != "" {
+		content = "<@&" + a.Cfg.ApplicationReminderRoleID + "> "
+		mentions.Roles = []string{a.Cfg.ApplicationReminderRoleID}
+	}
+	expiry := ""
+	if a.Cfg.ApplicationExpireAfter > 0 {
+		expiry = fmt.Sprintf(" They expire after %s.", a.Cfg.ApplicationExpireAfter)
+	}
+	content += fmt.Sprintf("⏰ %d whitelist application(s) are waiting for a decision.%s", len(apps), expiry)
+
+	if _, err := a.Session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		Embeds:          []*discordgo.MessageEmbed{{Title: "Pending applications", Description: truncate(strings.Join(items, "\n"), 4096), Color: 0xF59E0B}},
+		AllowedMentions: mentions,
+	}); err != nil {
+		logging.L().Error("remindStaff: posting reminder failed", "error", err)
+		return
+	}
+	now := time.Now()
+	for _, app := range apps {
+		if err := a.Applications.SetReminded(ctx, app.ID, now); err != nil {
+			logging.L().Warn("remindStaff: marking reminded failed", "application", app.ID, "error", err)
+		}
+	}
+}
+
+// expireApplication closes an undecided application, removes its buttons
+// and tells the applicant they may apply again.
+func (a *App) expireApplication(ctx context.Context, app *applications.Application) {
+	msg, err := a.Session.ChannelMessage(app.ChannelID, app.MessageID)
+	if err == nil && len(msg.Embeds) > 0 {
+		// An approval in progress or a pending conflict replaces the decision
+		// buttons; leave those to staff.
+		if _, _, ok := a.decisionTarget(ctx, msg); !ok {
+			return
+		}
+		cp := *msg.Embeds[0]
+		cp.Fields = append([]*discordgo.MessageEmbedField(nil), cp.Fields...)
+		statusLine := fmt.Sprintf("⌛ Request for `%s` **expired** without a decision. (Requested by: <@%s>)", app.MinecraftName, app.DiscordID)
+		cp.Description = strings.TrimSpace(cp.Description + "\n\n" + statusLine)
+		setEmbedField(&cp, "Decision", "Expired", false)
+		cp.Color = 0x6B7280
+		cp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+		embeds := []*discordgo.MessageEmbed{&cp}
+		components := []discordgo.MessageComponent{}
+		if _, err := a.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Channel:    app.ChannelID,
+			ID:         app.MessageID,
+			Embeds:     &embeds,
+			Components: &components,
+		}); err != nil {
+			logging.L().Warn("expireApplication: embed update failed", "application", app.ID, "error", err)
+		}
+	} else if err != nil && !isNotFound(err) {
+		logging.L().Warn("expireApplication: request message lookup failed", "application", app.ID, "error", err)
+		return
+	}
+
+	if err := a.Applications.SetStatus(ctx, app.ID, applications.StatusExpired, applications.DecidedByExpiry); err != nil {
+		logging.L().Error("expireApplication: status update failed", "application", app.ID, "error", err)
+		return
+	}
+	app.Status = applications.StatusExpired
+	logging.L().Info("whitelist application expired", "application", app.ID, "username", app.MinecraftName, "discord_id", app.DiscordID)
+	go a.closeApplicationThreads(app)
+
+	if dm, err := a.Session.UserChannelCreate(app.DiscordID); err == nil {
+		_, _ = a.Session.ChannelMessageSend(dm.ID, fmt.Sprintf(
+			"⌛ Your whitelist application for `%s` expired before staff could review it. Sorry about that! You are welcome to apply again with /whitelist.",
+			app.MinecraftName))
+	}
+}
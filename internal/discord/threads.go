@@ -0,0 +1,164 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+const (
+	threadArchiveMinutes = 10080
+	transcriptMaxPages   = 10
+)
+
+// openDiscussionThread starts a private staff thread for the request in the
+// staff channel, or in the requests channel when none is configured. Staff
+// who vote on the request are added to it.
+func (a *App) openDiscussionThread(ctx context.Context, app *applications.Application, msg *discordgo.Message) {
+	channelID := a.Cfg.StaffChannelID
+	if channelID == "" {
+		channelID = msg.ChannelID
+	}
+	th, err := a.Session.ThreadStartComplex(channelID, &discordgo.ThreadStart{
+		Name:                truncate(fmt.Sprintf("Application #%d – %s", app.ID, app.MinecraftName), 100),
+		AutoArchiveDuration: threadArchiveMinutes,
+		Type:                discordgo.ChannelTypeGuildPrivateThread,
+		Invitable:           false,
+	})
+	if err != nil {
+		logging.L().Error("openDiscussionThread: thread start failed", "application", app.ID, "error", err)
+		return
+	}
+	app.DiscussionThreadID = th.ID
+	if err := a.Applications.SetDiscussionThread(ctx, app.ID, th.ID); err != nil {
+		logging.L().Error("openDiscussionThread: saving thread id failed", "application", app.ID, "error", err)
+	}
+	link := messageLink(a.Cfg.GuildID, msg.ChannelID, msg.ID)
+	if _, err := a.Session.ChannelMessageSend(th.ID, fmt.Sprintf("Discussion of the whitelist request for `%s`: %s", app.MinecraftName, link)); err != nil {
+		logging.L().Warn("openDiscussionThread: intro message failed", "application", app.ID, "error", err)
+	}
+}
+
+// joinDiscussionThread adds a staff member to the request's discussion
+// thread.
+func (a *App) joinDiscussionThread(app *applications.Application, userID string) {
+	if app.DiscussionThreadID == "" {
+		return
+	}
+	if err := a.Session.ThreadMemberAdd(app.DiscussionThreadID, userID); err != nil {
+		logging.L().Warn("joinDiscussionThread: adding thread member failed", "thread", app.DiscussionThreadID, "user", userID, "error", err)
+	}
+}
+
+func (a *App) handleInterviewButton(i *discordgo.InteractionCreate) {
+	if a.Cfg.InterviewChannelID == "" {
+		a.reply(i, "Interviews are not configured.", true)
+		return
+	}
+	ctx := context.Background()
+	app, err := a.Applications.GetByMessage(ctx, i.Message.ID)
+	if err != nil || app == nil {
+		a.reply(i, "Could not load this application.", true)
+		return
+	}
+	if app.Status != applications.StatusPending {
+		a.reply(i, fmt.Sprintf("This application was already %s.", app.Status), true)
+		return
+	}
+	if app.InterviewThreadID != "" {
+		a.reply(i, fmt.Sprintf("Interview already open: <#%s>", app.InterviewThreadID), true)
+		return
+	}
+
+	th, err := a.Session.ThreadStartComplex(a.Cfg.InterviewChannelID, &discordgo.ThreadStart{
+		Name:                truncate(fmt.Sprintf("Interview #%d – %s", app.ID, app.MinecraftName), 100),
+		AutoArchiveDuration: threadArchiveMinutes,
+		Type:                discordgo.ChannelTypeGuildPrivateThread,
+		Invitable:           false,
+	})
+	if err != nil {
+		logging.L().Error("handleInterviewButton: thread start failed", "application", app.ID, "error", err)
+		a.reply(i, "Could not open an interview thread.", true)
+		return
+	}
+	for _, id := range []string{app.DiscordID, i.Member.User.ID} {
+		if err := a.Session.ThreadMemberAdd(th.ID, id); err != nil {
+			logging.L().Warn("handleInterviewButton: adding thread member failed", "thread", th.ID, "user", id, "error", err)
+		}
+	}
+	if err := a.Applications.SetInterviewThread(ctx, app.ID, th.ID); err != nil {
+		logging.L().Error("handleInterviewButton: saving thread id failed", "application", app.ID, "error", err)
+	}
+
+	_, _ = a.Session.ChannelMessageSend(th.ID, fmt.Sprintf(
+		"Hi <@%s>! <@%s> from the staff team has a few questions about your whitelist application for `%s`.",
+		app.DiscordID, i.Member.User.ID, app.MinecraftName,
+	))
+	if app.DiscussionThreadID != "" {
+		a.joinDiscussionThread(app, i.Member.User.ID)
+		_, _ = a.Session.ChannelMessageSend(app.DiscussionThreadID, fmt.Sprintf("🎤 <@%s> opened an interview: <#%s>", i.Member.User.ID, th.ID))
+	}
+	a.reply(i, fmt.Sprintf("Interview opened: <#%s>", th.ID), true)
+}
+
+// closeApplicationThreads saves a transcript of each thread and archives it.
+func (a *App) closeApplicationThreads(app *applications.Application) {
+	ctx := context.Background()
+	threads := []struct{ id, kind string }{
+		{app.DiscussionThreadID, applications.ThreadDiscussion},
+		{app.InterviewThreadID, applications.ThreadInterview},
+	}
+	for _, t := range threads {
+		if t.id == "" {
+			continue
+		}
+		content, err := a.threadTranscript(t.id)
+		if err != nil {
+			logging.L().Error("closeApplicationThreads: transcript fetch failed", "application", app.ID, "thread", t.id, "error", err)
+		} else if err := a.Applications.SaveTranscript(ctx, applications.Transcript{ApplicationID: app.ID, ThreadID: t.id, Kind: t.kind, Content: content}); err != nil {
+			logging.L().Error("closeApplicationThreads: transcript save failed", "application", app.ID, "thread", t.id, "error", err)
+		}
+
+		_, _ = a.Session.ChannelMessageSend(t.id, fmt.Sprintf("🔒 Application %s; this thread is now archived.", app.Status))
+		archived, locked := true, true
+		if _, err := a.Session.ChannelEditComplex(t.id, &discordgo.ChannelEdit{Archived: &archived, Locked: &locked}); err != nil {
+			logging.L().Warn("closeApplicationThreads: archive failed", "application", app.ID, "thread", t.id, "error", err)
+		}
+	}
+}
+
+// threadTranscript renders a thread's messages oldest first.
+func (a *App) threadTranscript(threadID string) (string, error) {
+	var msgs []*discordgo.Message
+	before := ""
+	for page := 0; page < transcriptMaxPages; page++ {
+		batch, err := a.Session.ChannelMessages(threadID, 100, before, "", "")
+		if err != nil {
+			return "", err
+		}
+		msgs = append(msgs, batch...)
+		if len(batch) < 100 {
+			break
+		}
+		before = batch[len(batch)-1].ID
+	}
+	slices.Reverse(msgs)
+
+	var b strings.Builder
+	for _, m := range msgs {
+		if m.Author == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s] %s (%s): %s", m.Timestamp.UTC().Format("2006-01-02 15:04"), m.Author.Username, m.Author.ID, m.Content)
+		for _, att := range m.Attachments {
+			b.WriteString(" " + att.URL)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
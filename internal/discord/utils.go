@@ -1,9 +1,14 @@
 package discord
 
 import (
+	"context"
+	"fmt"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
 )
 
 func ternary[T any](cond bool, a T, b T) T {
@@ -25,3 +30,97 @@ func modalValue(i *discordgo.InteractionCreate, id string) string {
 	}
 	return ""
 }
+
+// setEmbedField replaces the value of the named field, appending it if missing.
+func setEmbedField(e *discordgo.MessageEmbed, name, value string, inline bool) {
+	for _, f := range e.Fields {
+		if strings.EqualFold(f.Name, name) {
+			f.Value = value
+			return
+		}
+	}
+	e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: name, Value: value, Inline: inline})
+}
+
+func removeEmbedField(e *discordgo.MessageEmbed, name string) {
+	var out []*discordgo.MessageEmbedField
+	for _, f := range e.Fields {
+		if !strings.EqualFold(f.Name, name) {
+			out = append(out, f)
+		}
+	}
+	e.Fields = out
+}
+
+func embedFieldValue(e *discordgo.MessageEmbed, name string) string {
+	for _, f := range e.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return strings.Trim(f.Value, "`")
+		}
+	}
+	return ""
+}
+
+// runEvery calls fn on every tick until ctx is cancelled. A panic in one run
+// is logged and does not stop the schedule.
+func (a *App) runEvery(ctx context.Context, name string, every time.Duration, fn func(context.Context)) {
+	logging.L().Info("scheduled job started", "job", name, "interval", every)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						stack := make([]byte, 8192)
+						n := runtime.Stack(stack, false)
+						logging.L().Error("scheduled job panic", "job", name, "recover", r, "stack", string(stack[:n]))
+					}
+				}()
+				fn(ctx)
+			}()
+		}
+	}
+}
+
+func hasPermission(i *discordgo.InteractionCreate, perm int64) bool {
+	return i.Member != nil && i.Member.Permissions&perm == perm
+}
+
+// fieldList joins items for an embed field, staying under Discord's 1024 char limit.
+func fieldList(items []string) string {
+	if len(items) == 0 {
+		return "None"
+	}
+	var b strings.Builder
+	for n, it := range items {
+		line := "• " + it + "\n"
+		if b.Len()+len(line) > 980 {
+			fmt.Fprintf(&b, "…and %d more", len(items)-n)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
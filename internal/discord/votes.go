@@ -0,0 +1,113 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// quorumEnabled reports whether whitelist requests need more than one vote.
+func (a *App) quorumEnabled() bool {
+	return a.Cfg.WhitelistApprovalsRequired > 1 || a.Cfg.WhitelistRejectionsRequired > 1
+}
+
+// voteResult is the state of a request after castVote.
+type voteResult int
+
+const (
+	// voteFailed means castVote already replied to the interaction.
+	voteFailed voteResult = iota
+	votePending
+	voteApproved
+	voteRejected
+)
+
+// staffVoteRejectReason is sent to applicants whose request is rejected by
+// votes cast without a reason.
+const staffVoteRejectReason = "Your application did not receive enough staff support."
+
+// castVote records the moderator's vote on the request message msg and
+// writes the live tally into msg.Embeds[0]. The caller responds to the
+// interaction unless the result is voteFailed.
+func (a *App) castVote(i *discordgo.InteractionCreate, msg *discordgo.Message, vote string) voteResult {
+	ctx := context.Background()
+	voterID := i.Member.User.ID
+
+	app, err := a.Applications.GetByMessage(ctx, msg.ID)
+	if err != nil {
+		logging.L().Error("castVote: application lookup failed", "message", msg.ID, "error", err)
+		a.reply(i, "Could not load this application, please try again.", true)
+		return voteFailed
+	}
+	if app == nil {
+		// Posted before votes were stored; fall back to a single decision.
+		return ternary(vote == applications.VoteApprove, voteApproved, voteRejected)
+	}
+	if app.Status != applications.StatusPending {
+		a.reply(i, fmt.Sprintf("This application was already %s.", app.Status), true)
+		return voteFailed
+	}
+	if voterID == app.DiscordID {
+		a.reply(i, "You cannot vote on your own application.", true)
+		return voteFailed
+	}
+	if vote == applications.VoteVeto && a.Cfg.WhitelistVetoRoleID != "" && !slices.Contains(i.Member.Roles, a.Cfg.WhitelistVetoRoleID) {
+		a.reply(i, "You are not allowed to veto applications.", true)
+		return voteFailed
+	}
+
+	if err := a.Applications.CastVote(ctx, app.ID, voterID, vote); err != nil {
+		logging.L().Error("castVote: saving vote failed", "application", app.ID, "error", err)
+		a.reply(i, "Could not save your vote, please try again.", true)
+		return voteFailed
+	}
+	a.joinDiscussionThread(app, voterID)
+	votes, err := a.Applications.Votes(ctx, app.ID)
+	if err != nil {
+		logging.L().Error("castVote: loading votes failed", "application", app.ID, "error", err)
+		a.reply(i, "Vote saved, but the tally could not be loaded.", true)
+		return voteFailed
+	}
+	tally := applications.TallyVotes(votes)
+	logging.L().Info("whitelist vote", "application", app.ID, "voter", voterID, "vote", vote,
+		"approvals", len(tally.Approvers), "rejections", len(tally.Rejecters), "vetoes", len(tally.Vetoers))
+
+	cp := *msg.Embeds[0]
+	cp.Fields = append([]*discordgo.MessageEmbedField(nil), cp.Fields...)
+	setEmbedField(&cp, "Votes", a.tallyText(tally), false)
+	msg.Embeds[0] = &cp
+
+	switch {
+	case len(tally.Vetoers) > 0:
+		return voteRejected
+	case len(tally.Rejecters) >= max(a.Cfg.WhitelistRejectionsRequired, 1):
+		return voteRejected
+	case len(tally.Approvers) >= max(a.Cfg.WhitelistApprovalsRequired, 1):
+		return voteApproved
+	}
+	return votePending
+}
+
+func (a *App) tallyText(t applications.Tally) string {
+	lines := []string{
+		fmt.Sprintf("✅ %d/%d %s", len(t.Approvers), max(a.Cfg.WhitelistApprovalsRequired, 1), mentions(t.Approvers)),
+		fmt.Sprintf("❌ %d/%d %s", len(t.Rejecters), max(a.Cfg.WhitelistRejectionsRequired, 1), mentions(t.Rejecters)),
+	}
+	if len(t.Vetoers) > 0 {
+		lines = append(lines, "⛔ Veto "+mentions(t.Vetoers))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func mentions(ids []string) string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, "<@"+id+">")
+	}
+	return strings.Join(out, ", ")
+}
@@ -2,44 +2,139 @@ package discord
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/applications"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/policy"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
 )
 
 func (a *App) openWhitelistModal(i *discordgo.InteractionCreate) {
-	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	userID := interactionUserID(i)
+	until, err := a.Applications.Cooldown(context.Background(), userID)
+	if err != nil {
+		logging.L().Warn("openWhitelistModal: cooldown lookup failed", "user", userID, "error", err)
+	} else if !until.IsZero() {
+		a.reply(i, fmt.Sprintf("Your last application was rejected. You can apply again <t:%d:R>.", until.Unix()), true)
+		return
+	}
+	a.drafts.clear(userID)
+	a.openWhitelistStep(i, a.Forms.Current(), 0)
+}
+
+// openWhitelistStep shows one modal worth of questions. Forms longer than
+// Discord's five field limit are split into several steps.
+func (a *App) openWhitelistStep(i *discordgo.InteractionCreate, form *applications.Form, step int) {
+	steps := form.Steps()
+	if step < 0 || step >= len(steps) {
+		a.reply(i, "That application step no longer exists, please start again with /whitelist.", true)
+		return
+	}
+
+	var rows []discordgo.MessageComponent
+	for _, q := range steps[step] {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    q.ID,
+				Label:       truncate(q.Label, 45),
+				Style:       ternary(q.Style == "paragraph", discordgo.TextInputParagraph, discordgo.TextInputShort),
+				Placeholder: q.Placeholder,
+				Required:    q.Required,
+				MinLength:   q.MinLength,
+				MaxLength:   q.MaxLength,
+				Value:       a.drafts.value(interactionUserID(i), q.ID),
+			},
+		}})
+	}
+
+	title := form.Title
+	if len(steps) > 1 {
+		title = fmt.Sprintf("%s (%d/%d)", title, step+1, len(steps))
+	}
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
-			CustomID: "whitelist_modal",
-			Title:    "Whitelist Application",
-			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					discordgo.TextInput{CustomID: "mc_username", Label: "Minecraft Username", Style: discordgo.TextInputShort, Required: true},
-				}},
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					discordgo.TextInput{CustomID: "age", Label: "Age", Style: discordgo.TextInputShort, Required: true},
-				}},
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					discordgo.TextInput{CustomID: "plan", Label: "What will you do?", Style: discordgo.TextInputShort, Required: true},
-				}},
-			},
+			CustomID:   components.ID("whitelist_modal", a.putComponentState(context.Background(), whitelistStepState, stepPayload{Step: step})),
+			Title:      truncate(title, 45),
+			Components: rows,
 		},
-	})
+	}); err != nil {
+		logging.L().Error("openWhitelistStep: modal failed", "step", step, "error", err)
+	}
+}
+
+// handleWhitelistStepButton reopens the form at the step stored behind the
+// button.
+func (a *App) handleWhitelistStepButton(i *discordgo.InteractionCreate) {
+	var st stepPayload
+	if err := a.componentState(context.Background(), i.MessageComponentData().CustomID, whitelistStepState, &st); err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	step := st.Step
+	if step > 0 && !a.drafts.exists(interactionUserID(i)) {
+		a.reply(i, "Your application expired, please start again with /whitelist.", true)
+		return
+	}
+	a.openWhitelistStep(i, a.Forms.Current(), step)
 }
 
 func (a *App) handleWhitelistSubmit(i *discordgo.InteractionCreate) {
 	logging.L().Debug("handleWhitelistSubmit: guild and user", "guild", i.GuildID, "user", i.Member.User.ID)
 
-	username := modalValue(i, "mc_username")
-	age := modalValue(i, "age")
-	plan := modalValue(i, "plan")
-	logging.L().Debug("handleWhitelistSubmit: received form values", "username", username, "age", age, "plan", plan)
+	userID := i.Member.User.ID
+	form := a.Forms.Current()
+	steps := form.Steps()
+
+	var st stepPayload
+	if err := a.componentState(context.Background(), i.ModalSubmitData().CustomID, whitelistStepState, &st); err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	step := st.Step
+	if step >= len(steps) {
+		a.reply(i, "The application form changed while you were filling it in, please start again with /whitelist.", true)
+		return
+	}
+
+	var problems []string
+	for _, q := range steps[step] {
+		v := modalValue(i, q.ID)
+		a.drafts.set(userID, q.ID, v)
+		if p := q.Validate(v); p != "" {
+			problems = append(problems, p)
+		}
+	}
+	logging.L().Debug("handleWhitelistSubmit: received form step", "user", userID, "step", step, "problems", len(problems))
+
+	if len(problems) > 0 {
+		a.replyStepButton(i, "Please fix the following:\n• "+strings.Join(problems, "\n• "), step, "Try again")
+		return
+	}
+	if step+1 < len(steps) {
+		a.replyStepButton(i, fmt.Sprintf("Step %d of %d saved.", step+1, len(steps)), step+1, fmt.Sprintf("Continue (%d/%d)", step+2, len(steps)))
+		return
+	}
+
+	answers := make([]applications.Answer, 0, len(form.Questions))
+	for _, q := range form.Questions {
+		answers = append(answers, applications.Answer{QuestionID: q.ID, Label: q.Label, Value: a.drafts.value(userID, q.ID)})
+	}
+	a.drafts.clear(userID)
 
-	if username == "" || age == "" || plan == "" {
+	username := ""
+	for _, ans := range answers {
+		if ans.QuestionID == applications.UsernameQuestion {
+			username = ans.Value
+		}
+	}
+	if username == "" {
 		a.reply(i, "Missing required fields.", true)
 		return
 	}
@@ -53,43 +148,32 @@ func (a *App) handleWhitelistSubmit(i *discordgo.InteractionCreate) {
 
 	logging.L().Debug("handleWhitelistSubmit: resolved username to UUID", "username", username, "uuid", uuid)
 
-	embed := &discordgo.MessageEmbed{
-		Title:       "Whitelist Request",
-		Description: "A new whitelist request has been submitted.",
-		Color:       0x3B82F6,
-		Fields: []*discordgo.MessageEmbedField{
-			{Name: "Applicant", Value: "<@" + i.Member.User.ID + ">", Inline: true},
-			{Name: "Minecraft Username", Value: "`" + username + "`", Inline: true},
-			{Name: "UUID", Value: "`" + uuid + "`", Inline: true},
-			{Name: "Age", Value: age, Inline: true},
-			{Name: "Plan", Value: plan},
-		},
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Footer:    &discordgo.MessageEmbedFooter{Text: "Rotaria Whitelist"},
+	ctx := context.Background()
+	app := &applications.Application{DiscordID: userID, MinecraftName: username, MinecraftUUID: uuid, Answers: answers}
+	hits := a.screenApplication(ctx, i, app)
+	if err := a.Applications.Create(ctx, app); err != nil {
+		logging.L().Error("handleWhitelistSubmit: saving application failed", "error", err)
+		a.reply(i, "Could not save your application, please try again later.", true)
+		return
 	}
 
-	components := []discordgo.MessageComponent{
-		discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				discordgo.Button{
-					CustomID: "approve_" + username + "|" + i.Member.User.ID,
-					Label:    "Approve",
-					Style:    discordgo.SuccessButton,
-				},
-				discordgo.Button{
-					CustomID: "reject_" + username + "|" + i.Member.User.ID,
-					Label:    "Reject",
-					Style:    discordgo.DangerButton,
-				},
-			},
-		},
+	if policy.Rejected(hits) {
+		a.autoReject(ctx, i, app, hits)
+		return
+	}
+
+	embed := applicationEmbed(app)
+	if len(hits) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "⚠️ Policy flags", Value: hitList(hits)})
+		embed.Color = 0xF59E0B
 	}
+	components := a.decisionComponents(username, userID)
 
 	if a.Cfg.WhitelistRequestsChannelID == "" {
 		logging.L().Debug("handleWhitelistSubmit: WhitelistRequestsChannelID is empty; not sending embed")
 	} else {
 		logging.L().Debug("handleWhitelistSubmit: sending embed to channel", "channel", a.Cfg.WhitelistRequestsChannelID)
-		_, err := a.Session.ChannelMessageSendComplex(
+		msg, err := a.Session.ChannelMessageSendComplex(
 			a.Cfg.WhitelistRequestsChannelID,
 			&discordgo.MessageSend{
 				Embeds:     []*discordgo.MessageEmbed{embed},
@@ -98,129 +182,247 @@ func (a *App) handleWhitelistSubmit(i *discordgo.InteractionCreate) {
 		)
 		if err != nil {
 			logging.L().Error("handleWhitelistSubmit: ChannelMessageSendComplex failed", "error", err)
+		} else {
+			if err := a.Applications.SetMessage(ctx, app.ID, msg.ChannelID, msg.ID); err != nil {
+				logging.L().Error("handleWhitelistSubmit: saving message id failed", "application", app.ID, "error", err)
+			}
+			a.openDiscussionThread(ctx, app, msg)
 		}
 	}
 
 	a.reply(i, fmt.Sprintf("Submitted whitelist request for %s. Staff will review soon.", username), true)
 }
 
-func (a *App) handleWhitelistDecision(i *discordgo.InteractionCreate) {
-	if !a.Bridge.IsConnected() {
-		a.reply(i, "Minecraft server is not connected; cannot process whitelist decisions right now.", true)
-		return
+func applicationEmbed(app *applications.Application) *discordgo.MessageEmbed {
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Applicant", Value: "<@" + app.DiscordID + ">", Inline: true},
+		{Name: "Minecraft Username", Value: "`" + app.MinecraftName + "`", Inline: true},
+		{Name: "UUID", Value: "`" + app.MinecraftUUID + "`", Inline: true},
 	}
+	for _, ans := range app.Answers {
+		if ans.QuestionID == applications.UsernameQuestion || ans.Value == "" {
+			continue
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   truncate(ans.Label, 256),
+			Value:  truncate(ans.Value, 1024),
+			Inline: len(ans.Value) <= 32,
+		})
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "Whitelist Request",
+		Description: "A new whitelist request has been submitted.",
+		Color:       0x3B82F6,
+		Fields:      fields,
+		Timestamp:   app.CreatedAt.UTC().Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Rotaria Whitelist • Application #%d", app.ID)},
+	}
+}
+
+func (a *App) replyStepButton(i *discordgo.InteractionCreate, msg string, step int, label string) {
+	_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						CustomID: components.ID("whitelist_step", a.putComponentState(context.Background(), whitelistStepState, stepPayload{Step: step})),
+						Label:    label,
+						Style:    discordgo.PrimaryButton,
+					},
+				}},
+			},
+		},
+	})
+}
+
+func (a *App) handleWhitelistDecision(i *discordgo.InteractionCreate) {
 	custom := i.MessageComponentData().CustomID
-	approved := false
-	var prefix string
-	if strings.HasPrefix(custom, "approve_") {
-		approved = true
-		prefix = "approve_"
-	} else if strings.HasPrefix(custom, "reject_") {
-		prefix = "reject_"
-	} else {
+	var vote string
+	for _, v := range []string{"approve", "reject", "veto"} {
+		if componentAction(custom) == v || strings.HasPrefix(custom, v+"_") {
+			vote = v
+		}
+	}
+	if vote == "" {
 		return
 	}
 
-	payload := strings.TrimPrefix(custom, prefix)
-	parts := strings.SplitN(payload, "|", 2)
-	if len(parts) != 2 {
-		a.reply(i, "Malformed decision ID.", true)
+	d, err := a.decision(context.Background(), custom)
+	if err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	username, requesterID := d.Username, d.RequesterID
+	if len(i.Message.Embeds) == 0 {
+		a.reply(i, "Missing embed.", true)
 		return
 	}
-	username := parts[0]
-	requesterID := parts[1]
-
-	if len(i.Message.Embeds) > 0 {
-		cp := *i.Message.Embeds[0]
 
-		statusLine := fmt.Sprintf(
-			"📝 Request for `%s` was **%s** by <@%s>. (Requested by: <@%s>)",
-			username,
-			ternary(approved, "Approved", "Rejected"),
-			i.Member.User.ID,
-			requesterID,
-		)
+	// Rejections and vetoes ask for a reason first; the vote is cast when the
+	// reason modal is submitted.
+	if vote != "approve" {
+		a.openRejectReasons(i, vote)
+		return
+	}
 
-		if strings.TrimSpace(cp.Description) == "" {
-			cp.Description = statusLine
-		} else {
-			cp.Description += "\n\n" + statusLine
-		}
+	if !a.Bridge.IsConnected() {
+		a.reply(i, "Minecraft server is not connected; cannot process whitelist decisions right now.", true)
+		return
+	}
 
-		found := false
-		for _, f := range cp.Fields {
-			if strings.EqualFold(f.Name, "Decision") {
-				f.Value = ternary(approved, "Approved", "Rejected")
-				found = true
-				break
-			}
-		}
-		if !found {
-			cp.Fields = append(cp.Fields, &discordgo.MessageEmbedField{
-				Name:  "Decision",
-				Value: ternary(approved, "Approved", "Rejected"),
+	if a.quorumEnabled() {
+		switch a.castVote(i, i.Message, applications.VoteApprove) {
+		case voteFailed:
+			return
+		case votePending:
+			_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseUpdateMessage,
+				Data: &discordgo.InteractionResponseData{
+					Embeds:     i.Message.Embeds,
+					Components: i.Message.Components,
+				},
 			})
+			return
+		case voteRejected:
+			// Earlier rejections or a veto already decide the request, e.g.
+			// after the thresholds were lowered or a rejection failed to
+			// close it, so close it now instead of counting the approval.
+			a.rejectApplication(context.Background(), i.Message, username, requesterID, i.Member.User.ID, staffVoteRejectReason, "")
+			a.reply(i, fmt.Sprintf("The votes on this request already reject it, so `%s` was rejected.", username), true)
+			return
 		}
-		cp.Timestamp = time.Now().UTC().Format(time.RFC3339)
-		if approved {
-			cp.Color = 0x22C55E
-		} else {
-			cp.Color = 0xEF4444
-		}
+	}
 
-		_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseUpdateMessage,
-			Data: &discordgo.InteractionResponseData{
-				Embeds:     []*discordgo.MessageEmbed{&cp},
-				Components: []discordgo.MessageComponent{},
-			},
-		})
-	} else {
-		a.reply(i, "Missing embed.", true)
+	// Resolving the UUID and talking to the bridge can outlive the 3s interaction window.
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		logging.L().Error("handleWhitelistDecision: defer failed", "error", err)
+		return
+	}
 
+	ctx := context.Background()
+	uuid, err := a.NameMC.UsernameToUUID(username)
+	if err != nil {
+		logging.L().Error("handleWhitelistDecision: UsernameToUUID failed", "username", username, "error", err)
+		a.followup(i, fmt.Sprintf("Could not resolve username %q or UUID endpoint is down.", username), true)
+		return
 	}
 
-	if approved {
-		ctx := context.Background()
-		uuid, err := a.NameMC.UsernameToUUID(username)
-		if err != nil {
-			logging.L().Error("handleWhitelistDecision: UsernameToUUID failed", "username", username, "error", err)
-			a.reply(i, fmt.Sprintf("Could not resolve username %q or UUID endpoint is down.", username), true)
+	created, err := a.addWhitelistEntry(ctx, requesterID, uuid, username)
+	if err != nil {
+		var conflict *whitelist.ConflictError
+		if errors.As(err, &conflict) {
+			logging.L().Info("handleWhitelistDecision: whitelist conflict", "username", username, "requester", requesterID, "conflict", err)
+			a.showWhitelistConflict(i, username, requesterID, conflict)
 			return
 		}
+		a.escalate(incident{
+			Title: "Whitelist approval failed", Step: "database", Err: err,
+			GuildID: i.GuildID, ChannelID: i.ChannelID, MessageID: i.Message.ID,
+			Subject: username, Moderator: i.Member.User.ID,
+		})
+		a.followup(i, "Failed to save the whitelist entry. Staff have been notified; you can try again.", true)
+		return
+	}
 
-		/*
-			1. Try to whitelist user on minecraft, exit if failed
-			2. Try to add member role, exit if failed
-			3. Try to save entry to database, exit if failed
-			4. Try to rename guild user to minecraft username, Exit if failed
-		*/
-		if _, err := a.Bridge.SendCommand(ctx, fmt.Sprintf("whitelist add %s", username)); err != nil {
-			logging.L().Error("Failed to send whitelist add command to bridge", "error", err)
-			a.reply(i, fmt.Sprintf("Failed to send whitelist command to minecraft server, please try again or try contacting @<@%s>", "322015089529978880"), true)
-			return
-		}
+	sg := requestSaga(i, username, uuid, requesterID)
+	sg.CreatedEntry = created
+	a.startApproval(ctx, i, sg)
+}
 
-		if err := a.Session.GuildMemberRoleAdd(a.Cfg.GuildID, requesterID, a.Cfg.MemberRoleID); err != nil {
-			logging.L().Error("Failed to assign member role during whitelist decision", "error", err)
-			a.reply(i, fmt.Sprintf("Failed to assign member role, please try again or try contacting <@%s>", "322015089529978880"), true)
-			return
-		}
+// recordDecision stores the outcome on the application posted as the request
+// message and closes its threads. Requests posted before applications were
+// persisted have no row and nil is returned.
+func (a *App) recordDecision(messageID, moderatorID, status string) *applications.Application {
+	if messageID == "" {
+		return nil
+	}
+	ctx := context.Background()
+	app, err := a.Applications.GetByMessage(ctx, messageID)
+	if err != nil {
+		logging.L().Error("recordDecision: lookup failed", "message", messageID, "error", err)
+		return nil
+	}
+	if app == nil {
+		return nil
+	}
+	if err := a.Applications.SetStatus(ctx, app.ID, status, moderatorID); err != nil {
+		logging.L().Error("recordDecision: update failed", "application", app.ID, "status", status, "error", err)
+		return nil
+	}
+	app.Status = status
+	go a.closeApplicationThreads(app)
+	return app
+}
 
-		if err := a.WLStore.Add(ctx, requesterID, uuid, username); err != nil {
-			logging.L().Error("Failed to add whitelist entry to database", "error", err)
-			a.reply(i, fmt.Sprintf("Failed to assign member role, please try again or try contacting <@%s>", "322015089529978880"), true)
-			return
-		}
+// decisionComponents returns the buttons of an undecided request. They share
+// one state key holding the applicant, reused whenever they are rendered.
+func (a *App) decisionComponents(username, requesterID string) []discordgo.MessageComponent {
+	key := a.decisionKey(username, requesterID)
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			CustomID: components.ID("approve", key),
+			Label:    "Approve",
+			Style:    discordgo.SuccessButton,
+		},
+		discordgo.Button{
+			CustomID: components.ID("reject", key),
+			Label:    "Reject",
+			Style:    discordgo.DangerButton,
+		},
+	}
+	if a.quorumEnabled() {
+		buttons = append(buttons, discordgo.Button{
+			CustomID: components.ID("veto", key),
+			Label:    "Veto",
+			Style:    discordgo.SecondaryButton,
+		})
+	}
+	if a.Cfg.InterviewChannelID != "" {
+		buttons = append(buttons, discordgo.Button{
+			CustomID: components.ID("interview", key),
+			Label:    "Interview",
+			Style:    discordgo.PrimaryButton,
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
 
-		if err = a.Session.GuildMemberNickname(i.GuildID, requesterID, username); err != nil {
-			logging.L().Error("Failed to set guild member nickname during whitelist decision", "error", err)
-			a.reply(i, fmt.Sprintf("Failed to set your nickname, please try again or try contacting <@%s>", "322015089529978880"), true)
-		}
+// decisionKey returns the state key of an applicant's decision and conflict
+// buttons.
+func (a *App) decisionKey(username, requesterID string) string {
+	return a.keepComponentState(context.Background(), decisionState, requesterID+"|"+username,
+		decisionPayload{Username: username, RequesterID: requesterID})
+}
 
-		if dm, err := a.Session.UserChannelCreate(requesterID); err == nil {
-			_, _ = a.Session.ChannelMessageSend(dm.ID, fmt.Sprintf("✅ You have been whitelisted on Rotaria! Welcome to Rotaria, `%s` 🎉", username))
-		}
+func decisionEmbed(orig *discordgo.MessageEmbed, username, requesterID, moderatorID string, approved bool) *discordgo.MessageEmbed {
+	cp := *orig
+	cp.Fields = append([]*discordgo.MessageEmbedField(nil), orig.Fields...)
 
+	statusLine := fmt.Sprintf(
+		"📝 Request for `%s` was **%s** by <@%s>. (Requested by: <@%s>)",
+		username,
+		ternary(approved, "Approved", "Rejected"),
+		moderatorID,
+		requesterID,
+	)
+
+	if strings.TrimSpace(cp.Description) == "" {
+		cp.Description = statusLine
+	} else {
+		cp.Description += "\n\n" + statusLine
+	}
+
+	setEmbedField(&cp, "Decision", ternary(approved, "Approved", "Rejected"), false)
+	cp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if approved {
+		cp.Color = 0x22C55E
+	} else {
+		cp.Color = 0xEF4444
 	}
+	return &cp
 }
@@ -0,0 +1,167 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/approval"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// showWhitelistConflict swaps the approve/reject buttons for transfer/abort and
+// explains which existing link blocks the approval.
+func (a *App) showWhitelistConflict(i *discordgo.InteractionCreate, username, requesterID string, conflict *whitelist.ConflictError) {
+	cp := *i.Message.Embeds[0]
+	cp.Fields = append([]*discordgo.MessageEmbedField(nil), cp.Fields...)
+	setEmbedField(&cp, "Conflict", conflictSummary(conflict), false)
+	cp.Color = 0xF59E0B
+
+	key := a.decisionKey(username, requesterID)
+	embeds := []*discordgo.MessageEmbed{&cp}
+	buttons := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{CustomID: components.ID("wl_transfer", key), Label: "Transfer & Approve", Style: discordgo.PrimaryButton},
+			discordgo.Button{CustomID: components.ID("wl_abort", key), Label: "Abort", Style: discordgo.SecondaryButton},
+		}},
+	}
+	if _, err := a.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &embeds, Components: &buttons}); err != nil {
+		logging.L().Error("showWhitelistConflict: embed update failed", "error", err)
+	}
+	a.followup(i, "This approval conflicts with an existing whitelist entry. Transfer it to the applicant or abort.", true)
+}
+
+func conflictSummary(c *whitelist.ConflictError) string {
+	e := c.Entry
+	switch {
+	case errors.Is(c, whitelist.ErrDiscordLinked):
+		return fmt.Sprintf("Applicant is already linked to `%s` (`%s`).", e.Username, e.MinecraftUUID)
+	case errors.Is(c, whitelist.ErrUUIDLinked):
+		return fmt.Sprintf("This Minecraft account is already linked to <@%s> as `%s`.", e.DiscordID, e.Username)
+	case errors.Is(c, whitelist.ErrUsernameTaken):
+		return fmt.Sprintf("Username `%s` is stored for another account (`%s`, <@%s>).", e.Username, e.MinecraftUUID, e.DiscordID)
+	}
+	return c.Error()
+}
+
+func (a *App) handleWhitelistConflict(i *discordgo.InteractionCreate) {
+	custom := i.MessageComponentData().CustomID
+	if len(i.Message.Embeds) == 0 {
+		a.reply(i, "Missing embed.", true)
+		return
+	}
+	d, err := a.decision(context.Background(), custom)
+	if err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	username, requesterID := d.Username, d.RequesterID
+
+	if componentAction(custom) == "wl_abort" || strings.HasPrefix(custom, "wl_abort_") {
+		cp := *i.Message.Embeds[0]
+		removeEmbedField(&cp, "Conflict")
+		cp.Color = 0x3B82F6
+		_ = a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{&cp},
+				Components: a.decisionComponents(username, requesterID),
+			},
+		})
+		return
+	}
+
+	if !a.Bridge.IsConnected() {
+		a.reply(i, "Minecraft server is not connected; cannot process whitelist decisions right now.", true)
+		return
+	}
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		logging.L().Error("handleWhitelistConflict: defer failed", "error", err)
+		return
+	}
+
+	ctx := context.Background()
+	uuid := embedFieldValue(i.Message.Embeds[0], "UUID")
+	if uuid == "" {
+		var err error
+		if uuid, err = a.NameMC.UsernameToUUID(username); err != nil {
+			a.followup(i, fmt.Sprintf("Could not resolve username %q or UUID endpoint is down.", username), true)
+			return
+		}
+	}
+
+	// The release of the conflicting links runs as the saga's first step,
+	// so rolling the approval back restores them.
+	logging.L().Info("whitelist conflict transfer", "username", username, "uuid", uuid, "discord_id", requesterID, "moderator", i.Member.User.ID)
+	sg := requestSaga(i, username, uuid, requesterID)
+	sg.Transfer = true
+	a.startApproval(ctx, i, sg)
+}
+
+// releaseConflicts removes every link that blocks sg's whitelist row. Each
+// one is recorded on the saga before it is removed. A Minecraft account that
+// is being replaced is also removed from the server whitelist.
+func (a *App) releaseConflicts(ctx context.Context, sg *approval.Saga) error {
+	lookups := []func() (*whitelist.Entry, error){
+		func() (*whitelist.Entry, error) { return a.WLStore.GetByDiscord(ctx, sg.DiscordID) },
+		func() (*whitelist.Entry, error) { return a.WLStore.GetByUUID(ctx, sg.UUID) },
+		func() (*whitelist.Entry, error) { return a.WLStore.GetByUsername(ctx, sg.Username) },
+	}
+	for _, lookup := range lookups {
+		old, err := lookup()
+		if err != nil {
+			return err
+		}
+		if old == nil || (old.DiscordID == sg.DiscordID && old.MinecraftUUID == sg.UUID && old.Username == sg.Username) {
+			continue
+		}
+		if old.Username == sg.Username && old.MinecraftUUID != sg.UUID {
+			// The stored name is stale; refresh it instead of dropping that player's link.
+			if current, err := a.NameMC.UUIDToUsername(old.MinecraftUUID); err == nil && current != old.Username {
+				if err := a.WLStore.UpdateUsernameByUUID(ctx, old.MinecraftUUID, current); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		rel := approval.Released{DiscordID: old.DiscordID, UUID: old.MinecraftUUID, Username: old.Username, Unwhitelisted: old.MinecraftUUID != sg.UUID}
+		if rel.Unwhitelisted && !validPlayerName(old.Username) {
+			return approval.Permanent(fmt.Errorf("invalid player name %q", old.Username))
+		}
+		if err := a.Approvals.AddReleased(ctx, sg, rel); err != nil {
+			return err
+		}
+		if rel.Unwhitelisted {
+			if err := a.playerCommand(ctx, "unwhitelist", old.Username); err != nil {
+				logging.L().Warn("releaseConflicts: unwhitelist failed", "username", old.Username, "error", err)
+			}
+		}
+		if err := a.WLStore.Remove(ctx, old.DiscordID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreReleased gives back the links releaseConflicts removed. The saga's
+// own row has already been rolled back by then.
+func (a *App) restoreReleased(ctx context.Context, sg *approval.Saga) error {
+	for n := len(sg.Released) - 1; n >= 0; n-- {
+		r := sg.Released[n]
+		if err := a.WLStore.Add(ctx, r.DiscordID, r.UUID, r.Username); err != nil {
+			return fmt.Errorf("restore %s: %w", r.Username, err)
+		}
+		if r.Unwhitelisted {
+			if err := a.playerCommand(ctx, "whitelist add", r.Username); err != nil {
+				return fmt.Errorf("whitelist %s: %w", r.Username, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rotaria-smp/rotaria-bot/internal/approval"
+	"github.com/rotaria-smp/rotaria-bot/internal/components"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+const wlListPageSize = 15
+
+func newWLCommand(perm int64) *discordgo.ApplicationCommand {
+	target := []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Discord user", Required: false},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "ign", Description: "Minecraft username", Required: false, MaxLength: 16},
+	}
+	minPage := float64(1)
+	return &discordgo.ApplicationCommand{
+		Name:                     "wl",
+		Description:              "Manage the whitelist (admin only)",
+		DefaultMemberPermissions: &perm,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand, Name: "add", Description: "Whitelist a member without an application",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Discord user", Required: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "ign", Description: "Minecraft username", Required: true, MaxLength: 16},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "remove", Description: "Remove a whitelist entry", Options: target},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "info", Description: "Show a whitelist entry and its history", Options: target},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "List whitelist entries",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "filter", Description: "Part of a Minecraft name or Discord ID", Required: false, MaxLength: 32},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "page", Description: "Page number", Required: false, MinValue: &minPage},
+				},
+			},
+		},
+	}
+}
+
+func (a *App) handleWLCommand(i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	if sub.Name == "list" {
+		filter, page := "", 1
+		if o, ok := opts["filter"]; ok {
+			filter = strings.TrimSpace(o.StringValue())
+		}
+		if o, ok := opts["page"]; ok {
+			page = int(o.IntValue())
+		}
+		a.respondWLList(i, discordgo.InteractionResponseChannelMessageWithSource, filter, page)
+		return
+	}
+
+	s := a.Session
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := make([]byte, 8192)
+				n := runtime.Stack(stack, false)
+				logging.L().Error("wl panic", "recover", r, "stack", string(stack[:n]))
+				safe := "internal error during /wl"
+				_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &safe})
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var response string
+		switch sub.Name {
+		case "add":
+			response = a.wlAdd(ctx, i, opts["user"].UserValue(nil).ID, strings.TrimSpace(opts["ign"].StringValue()))
+		case "remove", "info":
+			entry, msg := a.wlTarget(ctx, opts)
+			switch {
+			case entry == nil:
+				response = msg
+			case sub.Name == "remove":
+				response = a.wlRemove(ctx, i, entry)
+			default:
+				response = a.wlInfo(ctx, entry)
+			}
+		}
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &response})
+	}()
+}
+
+// wlTarget finds the entry named by the user or ign option. When it returns
+// nil the string explains why.
+func (a *App) wlTarget(ctx context.Context, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) (*whitelist.Entry, string) {
+	var (
+		entry *whitelist.Entry
+		err   error
+		label string
+	)
+	if o, ok := opts["user"]; ok {
+		id := o.UserValue(nil).ID
+		label = "<@" + id + ">"
+		entry, err = a.WLStore.GetByDiscord(ctx, id)
+	} else if o, ok := opts["ign"]; ok {
+		name := strings.TrimSpace(o.StringValue())
+		label = "`" + name + "`"
+		entry, err = a.WLStore.GetByUsername(ctx, name)
+	} else {
+		return nil, "Provide one option: user or ign"
+	}
+	if err != nil {
+		logging.L().Error("wl: lookup failed", "target", label, "error", err)
+		return nil, fmt.Sprintf("Lookup failed for %s, please try again later.", label)
+	}
+	if entry == nil {
+		return nil, fmt.Sprintf("%s is not whitelisted.", label)
+	}
+	return entry, ""
+}
+
+// wlAdd whitelists a member directly, running the same steps as an approved
+// application.
+func (a *App) wlAdd(ctx context.Context, i *discordgo.InteractionCreate, discordID, name string) string {
+	actor := i.Member.User.ID
+	uuid, err := a.NameMC.UsernameToUUID(name)
+	if err != nil {
+		logging.L().Error("wl add: UsernameToUUID failed", "username", name, "error", err)
+		return fmt.Sprintf("Could not resolve username %q or UUID endpoint is down.", name)
+	}
+	created, err := a.addWhitelistEntry(ctx, discordID, uuid, name)
+	if err != nil {
+		var conflict *whitelist.ConflictError
+		if errors.As(err, &conflict) {
+			return "Cannot add: " + conflictSummary(conflict)
+		}
+		logging.L().Error("wl add: saving entry failed", "username", name, "error", err)
+		return "Failed to save the whitelist entry, please try again."
+	}
+
+	sg := &approval.Saga{GuildID: i.GuildID, DiscordID: discordID, Username: name, UUID: uuid, ModeratorID: actor, CreatedEntry: created}
+	base := manualAddEmbed(sg)
+	// The status message carries the retry and roll back buttons if a step
+	// fails, also after a restart.
+	if a.Cfg.StaffChannelID != "" {
+		if msg, err := a.Session.ChannelMessageSendEmbed(a.Cfg.StaffChannelID, base); err != nil {
+			logging.L().Warn("wl add: posting status message failed", "username", name, "error", err)
+		} else {
+			sg.ChannelID, sg.MessageID = msg.ChannelID, msg.ID
+		}
+	}
+	if err := a.Approvals.Create(ctx, sg); err != nil {
+		logging.L().Error("wl add: saving saga failed", "username", name, "error", err)
+		if created {
+			if err := a.WLStore.Remove(ctx, discordID); err != nil {
+				logging.L().Error("wl add: removing entry after saga failure failed", "discord_id", discordID, "error", err)
+			}
+		}
+		if sg.MessageID != "" {
+			_ = a.Session.ChannelMessageDelete(sg.ChannelID, sg.MessageID)
+		}
+		return "Could not start the whitelist steps, so nothing was changed. Please try again."
+	}
+	a.runApproval(ctx, sg, base)
+	a.audit(ctx, actor, "whitelist_add", discordID, fmt.Sprintf("%s (%s), %s", name, uuid, sg.Status))
+
+	if sg.Status != approval.StatusCompleted {
+		return fmt.Sprintf("⚠️ Whitelisting `%s` for <@%s> %s:\n%s", name, discordID, sg.Status, approvalStepsText(sg))
+	}
+	return fmt.Sprintf("✅ Whitelisted `%s` for <@%s>.\n%s", name, discordID, approvalStepsText(sg))
+}
+
+// manualAddEmbed is the staff status message of a /wl add.
+func manualAddEmbed(sg *approval.Saga) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       "Manual Whitelist Add",
+		Description: fmt.Sprintf("`%s` is being whitelisted for <@%s> by <@%s>.", sg.Username, sg.DiscordID, sg.ModeratorID),
+		Color:       0x3B82F6,
+		Fields:      []*discordgo.MessageEmbedField{{Name: "UUID", Value: "`" + sg.UUID + "`"}},
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: "Rotaria Whitelist"},
+	}
+}
+
+// wlRemove undoes a whitelist entry on the server, in the database and on
+// Discord.
+func (a *App) wlRemove(ctx context.Context, i *discordgo.InteractionCreate, entry *whitelist.Entry) string {
+	actor := i.Member.User.ID
+	var lines []string
+	if err := a.playerCommand(ctx, "unwhitelist", entry.Username); err != nil {
+		logging.L().Warn("wl remove: unwhitelist failed", "username", entry.Username, "error", err)
+		lines = append(lines, fmt.Sprintf("❌ Server whitelist: %v", err))
+	} else {
+		lines = append(lines, "✅ Server whitelist")
+	}
+	if err := a.WLStore.Remove(ctx, entry.DiscordID); err != nil {
+		logging.L().Error("wl remove: deleting entry failed", "discord_id", entry.DiscordID, "error", err)
+		lines = append(lines, fmt.Sprintf("❌ Database entry: %v", err))
+	} else {
+		lines = append(lines, "✅ Database entry")
+	}
+	if err := a.Session.GuildMemberRoleRemove(i.GuildID, entry.DiscordID, a.Cfg.MemberRoleID); err != nil && !isNotFound(err) {
+		logging.L().Warn("wl remove: role removal failed", "discord_id", entry.DiscordID, "error", err)
+		lines = append(lines, fmt.Sprintf("❌ Member role: %v", err))
+	} else {
+		lines = append(lines, "✅ Member role")
+	}
+	a.audit(ctx, actor, "whitelist_remove", entry.DiscordID, fmt.Sprintf("%s (%s)", entry.Username, entry.MinecraftUUID))
+	return fmt.Sprintf("Removed `%s` (<@%s>):\n%s", entry.Username, entry.DiscordID, strings.Join(lines, "\n"))
+}
+
+func (a *App) wlInfo(ctx context.Context, entry *whitelist.Entry) string {
+	lines := []string{
+		fmt.Sprintf("**Minecraft:** `%s`", entry.Username),
+		fmt.Sprintf("**UUID:** `%s`", entry.MinecraftUUID),
+		fmt.Sprintf("**Discord:** <@%s> (`%s`)", entry.DiscordID, entry.DiscordID),
+	}
+	history, err := a.Audit.ForTarget(ctx, entry.DiscordID, 5)
+	if err != nil {
+		logging.L().Warn("wl info: audit lookup failed", "discord_id", entry.DiscordID, "error", err)
+	}
+	if len(history) > 0 {
+		lines = append(lines, "**History:**")
+		for _, h := range history {
+			lines = append(lines, fmt.Sprintf("<t:%d:d> `%s` by <@%s> — %s", h.CreatedAt.Unix(), h.Action, h.ActorID, h.Detail))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// respondWLList answers with one page of the filtered whitelist. The page
+// buttons store the page and filter as component state that expires after an
+// hour.
+func (a *App) respondWLList(i *discordgo.InteractionCreate, typ discordgo.InteractionResponseType, filter string, page int) {
+	entries, err := a.WLStore.List(context.Background())
+	if err != nil {
+		logging.L().Error("wl list: List failed", "error", err)
+		a.reply(i, "Could not load the whitelist, please try again later.", true)
+		return
+	}
+	if filter != "" {
+		needle := strings.ToLower(filter)
+		var matched []whitelist.Entry
+		for _, e := range entries {
+			if strings.Contains(strings.ToLower(e.Username), needle) || strings.Contains(e.DiscordID, needle) {
+				matched = append(matched, e)
+			}
+		}
+		entries = matched
+	}
+
+	pages := max((len(entries)+wlListPageSize-1)/wlListPageSize, 1)
+	page = min(max(page, 1), pages)
+	start := (page - 1) * wlListPageSize
+	end := min(start+wlListPageSize, len(entries))
+
+	items := make([]string, 0, end-start)
+	for _, e := range entries[start:end] {
+		items = append(items, fmt.Sprintf("`%s` — <@%s>", e.Username, e.DiscordID))
+	}
+	desc := strings.Join(items, "\n")
+	if desc == "" {
+		desc = "No entries."
+	}
+	title := "Whitelist"
+	if filter != "" {
+		title += fmt.Sprintf(" matching %q", filter)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: desc,
+		Color:       0x3B82F6,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d/%d • %d entries", page, pages, len(entries))},
+	}
+	key := a.putComponentState(context.Background(), listPageState, listPagePayload{Page: page, Filter: filter})
+	buttons := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{CustomID: components.ID("wl_list_prev", key), Label: "Previous", Style: discordgo.SecondaryButton, Disabled: page <= 1},
+			discordgo.Button{CustomID: components.ID("wl_list_next", key), Label: "Next", Style: discordgo.SecondaryButton, Disabled: page >= pages},
+		}},
+	}
+	if err := a.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: typ,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: buttons,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		logging.L().Warn("wl list: respond failed", "error", err)
+	}
+}
+
+func (a *App) handleWLListButton(i *discordgo.InteractionCreate) {
+	cid := i.MessageComponentData().CustomID
+	var st listPagePayload
+	if err := a.componentState(context.Background(), cid, listPageState, &st); err != nil {
+		a.replyComponentStateError(i, err)
+		return
+	}
+	page := st.Page + ternary(componentAction(cid) == "wl_list_prev", -1, 1)
+	a.respondWLList(i, discordgo.InteractionResponseUpdateMessage, st.Filter, page)
+}
+
+func (a *App) audit(ctx context.Context, actorID, action, target, detail string) {
+	if err := a.Audit.Log(ctx, actorID, action, target, detail); err != nil {
+		logging.L().Error("audit: write failed", "action", action, "target", target, "error", err)
+	}
+}
@@ -0,0 +1,188 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Infraction kinds recorded by the bot.
+const (
+	BlacklistMinecraft = "blacklist_minecraft"
+	BlacklistDiscord   = "blacklist_discord"
+)
+
+// AutoModerator is the ModeratorID of punishments applied by the ladder.
+const AutoModerator = "auto"
+
+// Infraction is one offence. Discord and Minecraft identities share a row
+// when the account is linked, so points add up across both.
+type Infraction struct {
+	ID         int64
+	DiscordID  string
+	Player     string
+	PlayerUUID string
+	Kind       string
+	Points     int
+	Detail     string
+	CreatedAt  time.Time
+}
+
+func (s *Store) migrateInfractions(ctx context.Context) error {
+	return s.db.Migrate(ctx, `CREATE TABLE IF NOT EXISTS infractions (
+        id {{pk}},
+        discord_id TEXT NOT NULL DEFAULT '',
+        player TEXT NOT NULL DEFAULT '',
+        player_uuid TEXT NOT NULL DEFAULT '',
+        kind TEXT NOT NULL,
+        points INTEGER NOT NULL,
+        detail TEXT NOT NULL DEFAULT '',
+        created_at BIGINT NOT NULL
+    )`,
+		`CREATE INDEX IF NOT EXISTS infractions_discord ON infractions(discord_id)`,
+		`CREATE INDEX IF NOT EXISTS infractions_player ON infractions(player)`,
+	)
+}
+
+// AddInfraction records inf and sets its ID.
+func (s *Store) AddInfraction(ctx context.Context, inf *Infraction) error {
+	if inf.CreatedAt.IsZero() {
+		inf.CreatedAt = time.Now()
+	}
+	return s.db.QueryRowContext(ctx,
+		`INSERT INTO infractions(discord_id, player, player_uuid, kind, points, detail, created_at)
+         VALUES(?,?,?,?,?,?,?) RETURNING id`,
+		inf.DiscordID, inf.Player, inf.PlayerUUID, inf.Kind, inf.Points, inf.Detail, inf.CreatedAt.Unix(),
+	).Scan(&inf.ID)
+}
+
+// identityWhere matches rows of a Discord account or a player. Either may be
+// empty.
+const identityWhere = `((discord_id<>'' AND discord_id=?) OR (player<>'' AND LOWER(player)=LOWER(?)))`
+
+// Points sums the infraction points of an identity recorded since since.
+func (s *Store) Points(ctx context.Context, discordID, player string, since time.Time) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(points), 0) FROM infractions WHERE `+identityWhere+` AND created_at>=?`,
+		discordID, player, since.Unix(),
+	).Scan(&n)
+	return n, err
+}
+
+// Infractions returns the newest infractions of an identity.
+func (s *Store) Infractions(ctx context.Context, discordID, player string, limit int) ([]*Infraction, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, discord_id, player, player_uuid, kind, points, detail, created_at FROM infractions
+         WHERE `+identityWhere+` ORDER BY id DESC LIMIT ?`,
+		discordID, player, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Infraction
+	for rows.Next() {
+		var (
+			inf     Infraction
+			created int64
+		)
+		if err := rows.Scan(&inf.ID, &inf.DiscordID, &inf.Player, &inf.PlayerUUID, &inf.Kind, &inf.Points, &inf.Detail, &created); err != nil {
+			return nil, err
+		}
+		inf.CreatedAt = time.Unix(created, 0)
+		out = append(out, &inf)
+	}
+	return out, rows.Err()
+}
+
+// Weights maps infraction kinds to points. Kinds without a weight count 1.
+type Weights map[string]int
+
+func (w Weights) Of(kind string) int {
+	if n, ok := w[kind]; ok {
+		return n
+	}
+	return 1
+}
+
+// ParseWeights reads entries like "blacklist_minecraft=2".
+func ParseWeights(entries []string) (Weights, error) {
+	w := Weights{}
+	for _, e := range entries {
+		kind, n, ok := strings.Cut(e, "=")
+		points, err := strconv.Atoi(strings.TrimSpace(n))
+		if !ok || err != nil || points < 0 {
+			return nil, fmt.Errorf("invalid infraction weight %q, want kind=points", e)
+		}
+		w[strings.TrimSpace(kind)] = points
+	}
+	return w, nil
+}
+
+// Step is one rung of an escalation ladder: reaching Points applies Kind,
+// for Duration when the kind is timed.
+type Step struct {
+	Points   int
+	Kind     string
+	Duration time.Duration
+}
+
+// Ladder is sorted by Points.
+type Ladder []Step
+
+// ParseLadder reads entries like "3=warn", "6=mute:1h" or "15=ban".
+func ParseLadder(entries []string) (Ladder, error) {
+	var l Ladder
+	for _, e := range entries {
+		n, action, ok := strings.Cut(e, "=")
+		points, err := strconv.Atoi(strings.TrimSpace(n))
+		if !ok || err != nil || points <= 0 {
+			return nil, fmt.Errorf("invalid ladder step %q, want points=kind[:duration]", e)
+		}
+		kind, d, timed := strings.Cut(strings.TrimSpace(action), ":")
+		st := Step{Points: points, Kind: kind}
+		switch kind {
+		case Warn, Kick, Ban:
+			if timed {
+				return nil, fmt.Errorf("ladder step %q: %s takes no duration", e, kind)
+			}
+		case Mute, TempBan:
+			if st.Duration, err = ParseDuration(d); err != nil {
+				return nil, fmt.Errorf("ladder step %q: %w", e, err)
+			}
+			if st.Duration > MaxTimed {
+				return nil, fmt.Errorf("ladder step %q: %s can last at most 28 days", e, kind)
+			}
+		default:
+			return nil, fmt.Errorf("ladder step %q: unknown punishment %q", e, kind)
+		}
+		l = append(l, st)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Points < l[j].Points })
+	return l, nil
+}
+
+// Crossed returns the highest step reached when points go from before to
+// after, or nil when no threshold was crossed.
+func (l Ladder) Crossed(before, after int) *Step {
+	var hit *Step
+	for i := range l {
+		if l[i].Points > before && l[i].Points <= after {
+			hit = &l[i]
+		}
+	}
+	return hit
+}
+
+// Next returns the first step above points, or nil.
+func (l Ladder) Next(points int) *Step {
+	for i := range l {
+		if l[i].Points > points {
+			return &l[i]
+		}
+	}
+	return nil
+}
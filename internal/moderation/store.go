@@ -0,0 +1,198 @@
+// Package moderation records punishments applied to players, so timed ones
+// can be lifted later even across restarts, and the infractions that feed
+// the automatic escalation ladder.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+// Punishment kinds.
+const (
+	Warn    = "warn"
+	Kick    = "kick"
+	Mute    = "mute"
+	TempBan = "tempban"
+	Ban     = "ban"
+)
+
+// LiftedByExpiry is the LiftedBy of punishments that ran out.
+const LiftedByExpiry = "expiry"
+
+type Punishment struct {
+	ID          int64
+	Kind        string
+	Player      string
+	PlayerUUID  string
+	DiscordID   string
+	Reason      string
+	ModeratorID string
+	// ReportID links the punishment to the report it resolved, or 0.
+	ReportID  int64
+	CreatedAt time.Time
+	// ExpiresAt is zero for punishments that do not expire.
+	ExpiresAt time.Time
+	LiftedAt  time.Time
+	LiftedBy  string
+}
+
+// Active reports whether the punishment is still in force.
+func (p *Punishment) Active() bool {
+	return p.LiftedAt.IsZero() && (p.ExpiresAt.IsZero() || time.Now().Before(p.ExpiresAt))
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS punishments (
+        id {{pk}},
+        kind TEXT NOT NULL,
+        player TEXT NOT NULL DEFAULT '',
+        player_uuid TEXT NOT NULL DEFAULT '',
+        discord_id TEXT NOT NULL DEFAULT '',
+        reason TEXT NOT NULL,
+        moderator_id TEXT NOT NULL,
+        report_id BIGINT NOT NULL DEFAULT 0,
+        created_at BIGINT NOT NULL,
+        expires_at BIGINT NOT NULL DEFAULT 0,
+        lifted_at BIGINT NOT NULL DEFAULT 0,
+        lifted_by TEXT NOT NULL DEFAULT ''
+    )`,
+		`CREATE INDEX IF NOT EXISTS punishments_expiry ON punishments(lifted_at, expires_at)`,
+	); err != nil {
+		return nil, err
+	}
+	s := &Store{db: db}
+	if err := s.migrateInfractions(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create records p and sets its ID.
+func (s *Store) Create(ctx context.Context, p *Punishment) error {
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	return s.db.QueryRowContext(ctx,
+		`INSERT INTO punishments(kind, player, player_uuid, discord_id, reason, moderator_id, report_id, created_at, expires_at)
+         VALUES(?,?,?,?,?,?,?,?,?) RETURNING id`,
+		p.Kind, p.Player, p.PlayerUUID, p.DiscordID, p.Reason, p.ModeratorID, p.ReportID, p.CreatedAt.Unix(), unix(p.ExpiresAt),
+	).Scan(&p.ID)
+}
+
+// Lift marks the punishment as no longer in force.
+func (s *Store) Lift(ctx context.Context, id int64, by string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE punishments SET lifted_at=?, lifted_by=? WHERE id=?`, time.Now().Unix(), by, id)
+	return err
+}
+
+// Expired returns timed punishments whose time is up but that were not
+// lifted yet.
+func (s *Store) Expired(ctx context.Context, now time.Time) ([]*Punishment, error) {
+	return s.query(ctx, ` WHERE lifted_at=0 AND expires_at>0 AND expires_at<=? ORDER BY expires_at`, now.Unix())
+}
+
+// History returns the newest punishments of a Discord account or a player.
+func (s *Store) History(ctx context.Context, discordID, player string, limit int) ([]*Punishment, error) {
+	return s.query(ctx, ` WHERE `+identityWhere+` ORDER BY id DESC LIMIT ?`, discordID, player, limit)
+}
+
+// Active returns the punishments of the given kinds still in force against a
+// Discord account or a player, newest first. Either identity may be empty.
+func (s *Store) Active(ctx context.Context, discordID, player string, kinds ...string) ([]*Punishment, error) {
+	where := ` WHERE lifted_at=0 AND (expires_at=0 OR expires_at>?) AND ` + identityWhere
+	args := []any{time.Now().Unix(), discordID, player}
+	if len(kinds) > 0 {
+		where += ` AND kind IN (?` + strings.Repeat(`,?`, len(kinds)-1) + `)`
+		for _, k := range kinds {
+			args = append(args, k)
+		}
+	}
+	return s.query(ctx, where+` ORDER BY id DESC`, args...)
+}
+
+const selectColumns = `SELECT id, kind, player, player_uuid, discord_id, reason, moderator_id, report_id, created_at, expires_at, lifted_at, lifted_by FROM punishments`
+
+func (s *Store) query(ctx context.Context, where string, args ...any) ([]*Punishment, error) {
+	rows, err := s.db.QueryContext(ctx, selectColumns+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Punishment
+	for rows.Next() {
+		var (
+			p                          Punishment
+			created, expires, liftedAt int64
+		)
+		if err := rows.Scan(&p.ID, &p.Kind, &p.Player, &p.PlayerUUID, &p.DiscordID, &p.Reason, &p.ModeratorID, &p.ReportID,
+			&created, &expires, &liftedAt, &p.LiftedBy); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = time.Unix(created, 0)
+		p.ExpiresAt = fromUnix(expires)
+		p.LiftedAt = fromUnix(liftedAt)
+		out = append(out, &p)
+	}
+	return out, rows.Err()
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnix(v int64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.Unix(v, 0)
+}
+
+// MaxTimed is the longest mute or temp-ban. Both are applied on Discord as a
+// timeout, which cannot last longer; a Discord ban would make the member
+// leave and drop their whitelist entry.
+const MaxTimed = 28 * 24 * time.Hour
+
+// ParseDuration accepts Go durations plus days and weeks, e.g. "3d" or "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if n, unit := strings.TrimRight(s, "dw"), strings.TrimLeft(s, "0123456789"); unit == "d" || unit == "w" {
+		v, err := strconv.Atoi(n)
+		if err != nil || v <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		if unit == "w" {
+			v *= 7
+		}
+		return time.Duration(v) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q, use e.g. 30m, 12h, 3d or 2w", s)
+	}
+	return d, nil
+}
+
+// FormatDuration renders d the way ParseDuration reads it.
+func FormatDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d >= 7*day && d%(7*day) == 0:
+		return fmt.Sprintf("%dw", d/(7*day))
+	case d >= day && d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
@@ -0,0 +1,94 @@
+// Package panels remembers the persistent messages posted by /setup so they
+// can be edited in place later.
+package panels
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+// Panel kinds.
+const (
+	Whitelist = "whitelist"
+	Report    = "report"
+	Rules     = "rules"
+)
+
+var Kinds = []string{Whitelist, Report, Rules}
+
+type Panel struct {
+	Kind      string
+	ChannelID string
+	MessageID string
+	UpdatedAt time.Time
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS panels (
+        kind TEXT PRIMARY KEY,
+        channel_id TEXT NOT NULL,
+        message_id TEXT NOT NULL,
+        updated_at BIGINT NOT NULL
+    )`); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Save records where the panel of p.Kind is posted, replacing any previous one.
+func (s *Store) Save(ctx context.Context, p Panel) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO panels(kind, channel_id, message_id, updated_at) VALUES(?,?,?,?)
+         ON CONFLICT(kind) DO UPDATE SET channel_id=excluded.channel_id, message_id=excluded.message_id, updated_at=excluded.updated_at`,
+		p.Kind, p.ChannelID, p.MessageID, time.Now().Unix(),
+	)
+	return err
+}
+
+// Get returns the panel of the given kind, or nil if it was never posted.
+func (s *Store) Get(ctx context.Context, kind string) (*Panel, error) {
+	var p Panel
+	var updated int64
+	err := s.db.QueryRowContext(ctx, `SELECT kind, channel_id, message_id, updated_at FROM panels WHERE kind=?`, kind).
+		Scan(&p.Kind, &p.ChannelID, &p.MessageID, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.UpdatedAt = time.Unix(updated, 0)
+	return &p, nil
+}
+
+func (s *Store) List(ctx context.Context) ([]Panel, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, channel_id, message_id, updated_at FROM panels ORDER BY kind`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Panel
+	for rows.Next() {
+		var p Panel
+		var updated int64
+		if err := rows.Scan(&p.Kind, &p.ChannelID, &p.MessageID, &updated); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt = time.Unix(updated, 0)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) Delete(ctx context.Context, kind string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM panels WHERE kind=?`, kind)
+	return err
+}
@@ -0,0 +1,141 @@
+// Package policy screens whitelist applications against server rules before
+// staff see them.
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Action says what a matching rule does to the application.
+type Action string
+
+const (
+	Off    Action = "off"
+	Flag   Action = "flag"
+	Reject Action = "reject"
+)
+
+// ParseAction reads an action name, falling back to def for unknown values.
+func ParseAction(s string, def Action) Action {
+	switch Action(strings.ToLower(strings.TrimSpace(s))) {
+	case Off:
+		return Off
+	case Flag:
+		return Flag
+	case Reject:
+		return Reject
+	}
+	return def
+}
+
+const (
+	RuleMinAge          = "min_age"
+	RuleAccountAge      = "account_age"
+	RuleMembership      = "guild_membership"
+	RuleUsername        = "username"
+	RuleWhitelistedUUID = "whitelisted_uuid"
+	RuleReapply         = "reapply_cooldown"
+)
+
+// Rules holds thresholds and the action for each rule. A zero threshold
+// disables the rule regardless of its action.
+type Rules struct {
+	MinAge        int
+	MinAccountAge time.Duration
+	MinMembership time.Duration
+	Actions       map[string]Action
+	// AgeQuestion is the ID of the form question holding the applicant's age.
+	AgeQuestion string
+}
+
+// Input are the facts about one application. Zero values mean unknown and
+// never match.
+type Input struct {
+	Age             int
+	AccountCreated  time.Time
+	JoinedGuild     time.Time
+	UsernameBlocked bool
+	// WhitelistedBy is the Discord ID already linked to the UUID, if any.
+	WhitelistedBy string
+	// CooldownUntil is when a staff rejection's reapply cooldown ends.
+	CooldownUntil time.Time
+	Now           time.Time
+}
+
+type Hit struct {
+	Rule   string
+	Action Action
+	Reason string
+}
+
+func (r Rules) action(rule string) Action {
+	if a, ok := r.Actions[rule]; ok {
+		return a
+	}
+	return Off
+}
+
+// Evaluate returns every rule the application breaks, skipping rules that
+// are switched off.
+func (r Rules) Evaluate(applicantID string, in Input) []Hit {
+	if in.Now.IsZero() {
+		in.Now = time.Now()
+	}
+	var hits []Hit
+	add := func(rule, reason string) {
+		if a := r.action(rule); a != Off {
+			hits = append(hits, Hit{Rule: rule, Action: a, Reason: reason})
+		}
+	}
+
+	if r.MinAge > 0 && in.Age > 0 && in.Age < r.MinAge {
+		add(RuleMinAge, fmt.Sprintf("Applicants must be at least %d years old.", r.MinAge))
+	}
+	if r.MinAccountAge > 0 && !in.AccountCreated.IsZero() && in.Now.Sub(in.AccountCreated) < r.MinAccountAge {
+		add(RuleAccountAge, fmt.Sprintf("Your Discord account must be older than %s.", humanDuration(r.MinAccountAge)))
+	}
+	if r.MinMembership > 0 && !in.JoinedGuild.IsZero() && in.Now.Sub(in.JoinedGuild) < r.MinMembership {
+		add(RuleMembership, fmt.Sprintf("You must be a member of the Discord server for at least %s.", humanDuration(r.MinMembership)))
+	}
+	if in.UsernameBlocked {
+		add(RuleUsername, "Your Minecraft username is not allowed on this server.")
+	}
+	if in.WhitelistedBy != "" && in.WhitelistedBy != applicantID {
+		add(RuleWhitelistedUUID, "This Minecraft account is already whitelisted for someone else.")
+	}
+	if !in.CooldownUntil.IsZero() && in.Now.Before(in.CooldownUntil) {
+		add(RuleReapply, fmt.Sprintf("You can apply again <t:%d:R>.", in.CooldownUntil.Unix()))
+	}
+	return hits
+}
+
+// Rejected reports whether any hit auto-rejects.
+func Rejected(hits []Hit) bool {
+	for _, h := range hits {
+		if h.Action == Reject {
+			return true
+		}
+	}
+	return false
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		days := int(d / (24 * time.Hour))
+		return fmt.Sprintf("%d day%s", days, plural(days))
+	case d >= time.Hour && d%time.Hour == 0:
+		h := int(d / time.Hour)
+		return fmt.Sprintf("%d hour%s", h, plural(h))
+	}
+	return d.String()
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
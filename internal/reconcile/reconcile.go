@@ -0,0 +1,176 @@
+// Package reconcile compares the Minecraft server whitelist with the
+// whitelist table and describes how they have drifted apart.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// Commander sends console commands to the Minecraft server.
+type Commander interface {
+	SendCommand(ctx context.Context, body string) (string, error)
+}
+
+// Resolver maps a Minecraft username to its UUID.
+type Resolver interface {
+	UsernameToUUID(username string) (string, error)
+}
+
+type Player struct {
+	Name string
+	UUID string
+}
+
+// Report lists every difference found in a single run.
+type Report struct {
+	// ServerOnly are whitelisted in-game but have no database row.
+	ServerOnly []Player
+	// DBOnly have a database row but are missing from the server whitelist.
+	DBOnly []whitelist.Entry
+	// Renamed share a UUID but the database holds an old username.
+	Renamed []Rename
+	// Unresolved are server names whose UUID could not be looked up.
+	Unresolved []string
+}
+
+type Rename struct {
+	Entry   whitelist.Entry
+	NewName string
+}
+
+func (r Report) Empty() bool {
+	return len(r.ServerOnly) == 0 && len(r.DBOnly) == 0 && len(r.Renamed) == 0 && len(r.Unresolved) == 0
+}
+
+type Reconciler struct {
+	Bridge   Commander
+	Store    whitelist.Store
+	Resolver Resolver
+}
+
+// Run fetches both whitelists and diffs them by UUID.
+func (r *Reconciler) Run(ctx context.Context) (Report, error) {
+	out, err := r.Bridge.SendCommand(ctx, "whitelist list")
+	if err != nil {
+		return Report{}, fmt.Errorf("fetch server whitelist: %w", err)
+	}
+	names := ParseWhitelistList(out)
+
+	entries, err := r.Store.List(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("list database whitelist: %w", err)
+	}
+
+	byName := make(map[string]whitelist.Entry, len(entries))
+	for _, e := range entries {
+		byName[strings.ToLower(e.Username)] = e
+	}
+
+	var players []Player
+	var unresolved []string
+	for _, n := range names {
+		if e, ok := byName[strings.ToLower(n)]; ok {
+			players = append(players, Player{Name: n, UUID: e.MinecraftUUID})
+			continue
+		}
+		uuid, err := r.Resolver.UsernameToUUID(n)
+		if err != nil {
+			unresolved = append(unresolved, n)
+			continue
+		}
+		players = append(players, Player{Name: n, UUID: uuid})
+	}
+
+	rep := Diff(entries, players)
+	rep.Unresolved = unresolved
+	return rep, nil
+}
+
+// Diff compares database entries against server players by UUID.
+func Diff(entries []whitelist.Entry, players []Player) Report {
+	var rep Report
+
+	server := make(map[string]Player, len(players))
+	for _, p := range players {
+		server[whitelist.NormalizeUUID(p.UUID)] = p
+	}
+	db := make(map[string]whitelist.Entry, len(entries))
+	for _, e := range entries {
+		key := whitelist.NormalizeUUID(e.MinecraftUUID)
+		db[key] = e
+
+		p, ok := server[key]
+		if !ok {
+			rep.DBOnly = append(rep.DBOnly, e)
+			continue
+		}
+		if !strings.EqualFold(p.Name, e.Username) {
+			rep.Renamed = append(rep.Renamed, Rename{Entry: e, NewName: p.Name})
+		}
+	}
+	for key, p := range server {
+		if _, ok := db[key]; !ok {
+			rep.ServerOnly = append(rep.ServerOnly, p)
+		}
+	}
+	sort.Slice(rep.ServerOnly, func(i, j int) bool { return rep.ServerOnly[i].Name < rep.ServerOnly[j].Name })
+	return rep
+}
+
+// ParseWhitelistList extracts names from the vanilla `whitelist list` reply,
+// e.g. "There are 2 whitelisted player(s): Alice, Bob".
+func ParseWhitelistList(out string) []string {
+	i := strings.Index(out, ":")
+	if i < 0 {
+		return nil
+	}
+	var names []string
+	for _, n := range strings.Split(out[i+1:], ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// Fix applies the report: the database is the source of truth for linked
+// players, so missing players are whitelisted in-game, unlinked server
+// entries are removed and stale usernames are updated. Names that are not
+// Minecraft usernames are never sent to the server and are reported as
+// errors.
+func (r *Reconciler) Fix(ctx context.Context, rep Report) []error {
+	var errs []error
+	for _, e := range rep.DBOnly {
+		if !whitelist.ValidUsername(e.Username) {
+			errs = append(errs, fmt.Errorf("whitelist add %q: invalid player name", e.Username))
+			continue
+		}
+		if _, err := r.Bridge.SendCommand(ctx, fmt.Sprintf("whitelist add %s", e.Username)); err != nil {
+			errs = append(errs, fmt.Errorf("whitelist add %s: %w", e.Username, err))
+		}
+	}
+	for _, p := range rep.ServerOnly {
+		if !whitelist.ValidUsername(p.Name) {
+			errs = append(errs, fmt.Errorf("unwhitelist %q: invalid player name", p.Name))
+			continue
+		}
+		if _, err := r.Bridge.SendCommand(ctx, fmt.Sprintf("unwhitelist %s", p.Name)); err != nil {
+			errs = append(errs, fmt.Errorf("unwhitelist %s: %w", p.Name, err))
+		}
+	}
+	for _, rn := range rep.Renamed {
+		if !whitelist.ValidUsername(rn.NewName) {
+			errs = append(errs, fmt.Errorf("rename %s to %q: invalid player name", rn.Entry.Username, rn.NewName))
+			continue
+		}
+		if err := r.Store.UpdateUsernameByUUID(ctx, rn.Entry.MinecraftUUID, rn.NewName); err != nil {
+			errs = append(errs, fmt.Errorf("rename %s: %w", rn.Entry.Username, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,31 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+type recorder struct{ cmds []string }
+
+func (r *recorder) SendCommand(_ context.Context, body string) (string, error) {
+	r.cmds = append(r.cmds, body)
+	return "", nil
+}
+
+func TestFixSkipsInvalidNames(t *testing.T) {
+	bridge := &recorder{}
+	r := &Reconciler{Bridge: bridge}
+	errs := r.Fix(context.Background(), Report{
+		DBOnly:     []whitelist.Entry{{Username: "Alice"}, {Username: "@a"}},
+		ServerOnly: []Player{{Name: "Bob"}, {Name: "Bob op Eve"}},
+	})
+	want := []string{"whitelist add Alice", "unwhitelist Bob"}
+	if len(bridge.cmds) != len(want) || bridge.cmds[0] != want[0] || bridge.cmds[1] != want[1] {
+		t.Errorf("commands = %q, want %q", bridge.cmds, want)
+	}
+	if len(errs) != 2 {
+		t.Errorf("got %d errors, want 2: %v", len(errs), errs)
+	}
+}
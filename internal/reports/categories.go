@@ -0,0 +1,185 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+)
+
+// Field IDs that fill the matching Report columns. Any other field is kept
+// in Report.Extra.
+const (
+	FieldPlayer   = "player"
+	FieldDetails  = "details"
+	FieldEvidence = "evidence"
+	FieldContext  = "context"
+)
+
+// Discord's limits for select menus and modals. MaxFieldLength is the
+// longest embed field value, so every answer fits on the staff embed.
+const (
+	MaxCategories        = 25
+	MaxFieldsPerCategory = 5
+	MaxFieldLength       = 1024
+)
+
+type Field struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Style       string `json:"style"` // "short" or "paragraph"
+	Placeholder string `json:"placeholder,omitempty"`
+	Required    bool   `json:"required"`
+	MaxLength   int    `json:"max_length,omitempty"`
+}
+
+type Category struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+	Emoji       string `json:"emoji,omitempty"`
+	// ChannelID is a text or forum channel; empty uses REPORT_CHANNEL_ID.
+	ChannelID string `json:"channel_id,omitempty"`
+	// ForumTags are tag names applied when ChannelID is a forum.
+	ForumTags []string `json:"forum_tags,omitempty"`
+	Fields    []Field  `json:"fields"`
+}
+
+type Categories struct {
+	Categories []Category `json:"categories"`
+}
+
+// Get returns the category with the given ID, or nil.
+func (c *Categories) Get(id string) *Category {
+	for n := range c.Categories {
+		if c.Categories[n].ID == id {
+			return &c.Categories[n]
+		}
+	}
+	return nil
+}
+
+// DefaultCategories are used when no categories file is configured.
+func DefaultCategories() *Categories {
+	details := Field{ID: FieldDetails, Label: "Details", Style: "paragraph", Required: true, MaxLength: 1000}
+	evidence := Field{ID: FieldEvidence, Label: "Evidence (links)", Style: "short", MaxLength: 200}
+	return &Categories{Categories: []Category{
+		{
+			ID: "player", Label: "Player", Description: "Griefing, cheating or harassment", Emoji: "🚨",
+			Fields: []Field{
+				{ID: FieldPlayer, Label: "Player", Style: "short", Required: true, MaxLength: 64},
+				details, evidence,
+				{ID: FieldContext, Label: "Context (optional)", Style: "short", MaxLength: 200},
+			},
+		},
+		{ID: "bug", Label: "Bug", Description: "Something on the server is broken", Emoji: "🐛", Fields: []Field{details, evidence}},
+		{ID: "other", Label: "Other", Description: "Anything else staff should know", Emoji: "📝", Fields: []Field{details, evidence}},
+	}}
+}
+
+func (c *Categories) compile() error {
+	if len(c.Categories) == 0 {
+		return errors.New("no categories")
+	}
+	if len(c.Categories) > MaxCategories {
+		return fmt.Errorf("at most %d categories are allowed", MaxCategories)
+	}
+	seen := map[string]bool{}
+	for n := range c.Categories {
+		cat := &c.Categories[n]
+		cat.ID, cat.Label = strings.TrimSpace(cat.ID), strings.TrimSpace(cat.Label)
+		if cat.ID == "" || cat.Label == "" {
+			return fmt.Errorf("category %d: id and label are required", n+1)
+		}
+		if strings.Contains(cat.ID, "|") {
+			return fmt.Errorf("category %q: id must not contain |", cat.ID)
+		}
+		if seen[cat.ID] {
+			return fmt.Errorf("category %q defined twice", cat.ID)
+		}
+		seen[cat.ID] = true
+		if len(cat.Fields) == 0 || len(cat.Fields) > MaxFieldsPerCategory {
+			return fmt.Errorf("category %q: needs 1 to %d fields", cat.ID, MaxFieldsPerCategory)
+		}
+		fields := map[string]bool{}
+		for f := range cat.Fields {
+			fd := &cat.Fields[f]
+			if fd.ID == "" || fd.Label == "" {
+				return fmt.Errorf("category %q field %d: id and label are required", cat.ID, f+1)
+			}
+			if fields[fd.ID] {
+				return fmt.Errorf("category %q: field %q defined twice", cat.ID, fd.ID)
+			}
+			fields[fd.ID] = true
+			if fd.MaxLength < 0 || fd.MaxLength > MaxFieldLength {
+				return fmt.Errorf("category %q field %q: max_length must be at most %d", cat.ID, fd.ID, MaxFieldLength)
+			}
+			if fd.Style != "paragraph" {
+				fd.Style = "short"
+			}
+		}
+	}
+	return nil
+}
+
+// CategorySource serves the categories defined in a JSON file and reloads
+// them when the file changes. A broken file keeps the last good categories.
+type CategorySource struct {
+	path string
+
+	mu      sync.Mutex
+	cats    *Categories
+	modTime time.Time
+}
+
+func NewCategorySource(path string) *CategorySource {
+	return &CategorySource{path: path, cats: DefaultCategories()}
+}
+
+func (s *CategorySource) Current() *Categories {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		return s.cats
+	}
+	st, err := os.Stat(s.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.L().Warn("report categories stat failed", "path", s.path, "error", err)
+		}
+		return s.cats
+	}
+	if st.ModTime().Equal(s.modTime) {
+		return s.cats
+	}
+	c, err := LoadCategories(s.path)
+	if err != nil {
+		logging.L().Error("report categories reload failed; keeping previous categories", "path", s.path, "error", err)
+		s.modTime = st.ModTime()
+		return s.cats
+	}
+	logging.L().Info("report categories loaded", "path", s.path, "categories", len(c.Categories))
+	s.cats, s.modTime = c, st.ModTime()
+	return s.cats
+}
+
+func LoadCategories(path string) (*Categories, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Categories
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := c.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,28 @@
+package reports
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCategoriesRejectsLongFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.json")
+	data := `{"categories": [{"id": "bug", "label": "Bug", "fields": [
+		{"id": "details", "label": "Details", "style": "paragraph", "max_length": 4000}
+	]}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadCategories(path)
+	if err == nil || !strings.Contains(err.Error(), "max_length") {
+		t.Fatalf("LoadCategories = %v, want a max_length error", err)
+	}
+}
+
+func TestLoadCategoriesAcceptsExample(t *testing.T) {
+	if _, err := LoadCategories(filepath.Join("..", "..", "report_categories.example.json")); err != nil {
+		t.Fatalf("LoadCategories(example) = %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package reports stores player, bug and other reports filed through the bot
+// so staff can track them after the embed scrolls away.
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
+)
+
+const (
+	StatusOpen      = "open"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+// ErrClosed is returned by Close when the report is no longer open, e.g.
+// because another moderator closed it first.
+var ErrClosed = errors.New("report already closed")
+
+// Report sources.
+const (
+	SourceDiscord   = "discord"
+	SourceMinecraft = "minecraft"
+)
+
+type Report struct {
+	ID int64
+	// ReporterID is the reporter's Discord ID. In-game reports from players
+	// without a linked account leave it empty and only set ReporterName.
+	ReporterID   string
+	ReporterName string
+	Source       string
+	Type         string
+	TargetPlayer string
+	TargetUUID   string
+	Details      string
+	Evidence     string
+	Context      string
+	// Location is where an in-game report was filed, e.g. "12, 64, -30 (overworld)".
+	Location string
+	// Extra holds the answers to category fields without a column of their own.
+	Extra      []Answer
+	Status     string
+	AssigneeID string
+	// Notes is the internal moderator note written when the report was closed.
+	Notes string
+	// PublicNote is the optional message sent to the reporter on close.
+	PublicNote string
+	ClosedBy   string
+	ChannelID  string
+	MessageID  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ClosedAt   time.Time
+}
+
+type Answer struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Filter narrows List. Empty fields match everything.
+type Filter struct {
+	Status     string
+	AssigneeID string
+	ReporterID string
+	Target     string
+	Limit      int
+	Offset     int
+}
+
+type Store struct {
+	db *sqldb.DB
+}
+
+func New(db *sqldb.DB) (*Store, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS reports (
+        id {{pk}},
+        reporter_id TEXT NOT NULL,
+        type TEXT NOT NULL,
+        target_player TEXT NOT NULL DEFAULT '',
+        target_uuid TEXT NOT NULL DEFAULT '',
+        details TEXT NOT NULL DEFAULT '',
+        evidence TEXT NOT NULL DEFAULT '',
+        context TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL,
+        assignee_id TEXT NOT NULL DEFAULT '',
+        notes TEXT NOT NULL DEFAULT '',
+        closed_by TEXT NOT NULL DEFAULT '',
+        channel_id TEXT NOT NULL DEFAULT '',
+        message_id TEXT NOT NULL DEFAULT '',
+        created_at BIGINT NOT NULL,
+        updated_at BIGINT NOT NULL,
+        closed_at BIGINT NOT NULL DEFAULT 0
+    )`,
+		`CREATE INDEX IF NOT EXISTS reports_status ON reports(status)`,
+		`CREATE INDEX IF NOT EXISTS reports_message_id ON reports(message_id)`,
+		`CREATE INDEX IF NOT EXISTS reports_target_player ON reports(target_player)`,
+		`CREATE TABLE IF NOT EXISTS report_notify_optout (
+        discord_id TEXT PRIMARY KEY,
+        created_at BIGINT NOT NULL
+    )`,
+	); err != nil {
+		return nil, err
+	}
+	for _, col := range []string{"public_note", "reporter_name", "location", "extra"} {
+		if err := db.AddColumn(context.Background(), "reports", col, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.AddColumn(context.Background(), "reports", "source", "TEXT NOT NULL DEFAULT 'discord'"); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Create inserts an open report and sets its ID and timestamps.
+func (s *Store) Create(ctx context.Context, r *Report) error {
+	now := time.Now()
+	r.Status = StatusOpen
+	r.CreatedAt, r.UpdatedAt = now, now
+	if r.Source == "" {
+		r.Source = SourceDiscord
+	}
+	extra, err := encodeExtra(r.Extra)
+	if err != nil {
+		return err
+	}
+	return s.db.QueryRowContext(ctx,
+		`INSERT INTO reports(reporter_id, reporter_name, source, type, target_player, target_uuid, details, evidence, context, location, extra, status, created_at, updated_at)
+         VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?) RETURNING id`,
+		r.ReporterID, r.ReporterName, r.Source, r.Type, r.TargetPlayer, r.TargetUUID, r.Details, r.Evidence, r.Context, r.Location, extra, r.Status, now.Unix(), now.Unix(),
+	).Scan(&r.ID)
+}
+
+func (s *Store) SetMessage(ctx context.Context, id int64, channelID, messageID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE reports SET channel_id=?, message_id=?, updated_at=? WHERE id=?`,
+		channelID, messageID, time.Now().Unix(), id)
+	return err
+}
+
+// Assign sets the staff member working on the report; "" unassigns it.
+func (s *Store) Assign(ctx context.Context, id int64, assigneeID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE reports SET assignee_id=?, updated_at=? WHERE id=?`,
+		assigneeID, time.Now().Unix(), id)
+	return err
+}
+
+// Close resolves or dismisses the open report with an internal note and an
+// optional note for the reporter. It returns ErrClosed if the report was
+// already closed.
+func (s *Store) Close(ctx context.Context, id int64, status, by, notes, publicNote string) error {
+	now := time.Now().Unix()
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE reports SET status=?, closed_by=?, notes=?, public_note=?, closed_at=?, updated_at=? WHERE id=? AND status=?`,
+		status, by, notes, publicNote, now, now, id, StatusOpen,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrClosed
+	}
+	return nil
+}
+
+// SetNotify records whether a reporter wants DMs about their reports.
+func (s *Store) SetNotify(ctx context.Context, discordID string, enabled bool) error {
+	if enabled {
+		_, err := s.db.ExecContext(ctx, `DELETE FROM report_notify_optout WHERE discord_id=?`, discordID)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO report_notify_optout(discord_id, created_at) VALUES(?,?) ON CONFLICT(discord_id) DO NOTHING`,
+		discordID, time.Now().Unix(),
+	)
+	return err
+}
+
+// NotifyEnabled reports whether the reporter should be DMed; everyone is
+// opted in until they opt out.
+func (s *Store) NotifyEnabled(ctx context.Context, discordID string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM report_notify_optout WHERE discord_id=?`, discordID).Scan(&n)
+	return n == 0, err
+}
+
+// Reopen puts a closed report back in the queue. The previous note is kept.
+func (s *Store) Reopen(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE reports SET status=?, closed_by='', closed_at=0, updated_at=? WHERE id=?`,
+		StatusOpen, time.Now().Unix(), id,
+	)
+	return err
+}
+
+// Get returns the report, or nil if it does not exist.
+func (s *Store) Get(ctx context.Context, id int64) (*Report, error) {
+	return scanReport(s.db.QueryRowContext(ctx, selectColumns+` WHERE id=?`, id))
+}
+
+// GetByMessage returns the report posted as messageID, or nil.
+func (s *Store) GetByMessage(ctx context.Context, messageID string) (*Report, error) {
+	return scanReport(s.db.QueryRowContext(ctx, selectColumns+` WHERE message_id=?`, messageID))
+}
+
+// List returns matching reports, newest first.
+func (s *Store) List(ctx context.Context, f Filter) ([]*Report, error) {
+	var where []string
+	var args []any
+	if f.Status != "" {
+		where = append(where, "status=?")
+		args = append(args, f.Status)
+	}
+	if f.AssigneeID != "" {
+		where = append(where, "assignee_id=?")
+		args = append(args, f.AssigneeID)
+	}
+	if f.ReporterID != "" {
+		where = append(where, "reporter_id=?")
+		args = append(args, f.ReporterID)
+	}
+	if f.Target != "" {
+		where = append(where, "LOWER(target_player)=LOWER(?)")
+		args = append(args, f.Target)
+	}
+	q := selectColumns
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		q += " LIMIT ? OFFSET ?"
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Report
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CountByStatus returns the number of reports in each status.
+func (s *Store) CountByStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM reports GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		out[status] = n
+	}
+	return out, rows.Err()
+}
+
+const selectColumns = `SELECT id, reporter_id, reporter_name, source, type, target_player, target_uuid, details, evidence, context, location, extra, status, assignee_id, notes, public_note, closed_by, channel_id, message_id, created_at, updated_at, closed_at FROM reports`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReport(row scanner) (*Report, error) {
+	var (
+		r                          Report
+		extra                      string
+		created, updated, closedAt int64
+	)
+	if err := row.Scan(&r.ID, &r.ReporterID, &r.ReporterName, &r.Source, &r.Type, &r.TargetPlayer, &r.TargetUUID, &r.Details, &r.Evidence, &r.Context, &r.Location, &extra,
+		&r.Status, &r.AssigneeID, &r.Notes, &r.PublicNote, &r.ClosedBy, &r.ChannelID, &r.MessageID, &created, &updated, &closedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if extra != "" {
+		if err := json.Unmarshal([]byte(extra), &r.Extra); err != nil {
+			return nil, err
+		}
+	}
+	r.CreatedAt = time.Unix(created, 0)
+	r.UpdatedAt = time.Unix(updated, 0)
+	if closedAt > 0 {
+		r.ClosedAt = time.Unix(closedAt, 0)
+	}
+	return &r, nil
+}
+
+func encodeExtra(extra []Answer) (string, error) {
+	if len(extra) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(extra)
+	return string(b), err
+}
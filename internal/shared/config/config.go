@@ -2,15 +2,21 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rotaria-smp/rotaria-bot/internal/shared/logging"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
 )
 
 type Config struct {
 	DiscordToken                       string
 	WSAddr                             string
+	DBDriver                           string
 	DBPath                             string
+	DatabaseURL                        string
 	DiscordWebhookURL                  string
 	BlacklistPath                      string
 	GuildID                            string
@@ -19,6 +25,42 @@ type Config struct {
 	WhitelistRequestsChannelID         string
 	MinecraftDiscordMessengerChannelID string
 	ServerStatusChannelID              string
+	StaffChannelID                     string
+	ReconcileInterval                  time.Duration
+	BackupDir                          string
+	BackupInterval                     time.Duration
+	BackupKeep                         int
+	ApplicationFormPath                string
+	PolicyMinAge                       int
+	PolicyMinAgeAction                 string
+	PolicyAgeQuestion                  string
+	PolicyMinAccountAge                time.Duration
+	PolicyAccountAgeAction             string
+	PolicyMinMembership                time.Duration
+	PolicyMembershipAction             string
+	PolicyUsernameAction               string
+	PolicyWhitelistedUUIDAction        string
+	PolicyReapplyCooldown              time.Duration
+	PolicyReapplyAction                string
+	WhitelistApprovalsRequired         int
+	WhitelistRejectionsRequired        int
+	WhitelistVetoRoleID                string
+	InterviewChannelID                 string
+	RejectionReasons                   []string
+	EscalationChannelID                string
+	EscalationRoleID                   string
+	EscalationUserIDs                  []string
+	RulesPath                          string
+	RulesRoleID                        string
+	ApplicationRemindAfter             time.Duration
+	ApplicationExpireAfter             time.Duration
+	ApplicationCheckInterval           time.Duration
+	ApplicationReminderRoleID          string
+	PunishmentCheckInterval            time.Duration
+	InfractionWeights                  []string
+	InfractionDecay                    time.Duration
+	InfractionLadder                   []string
+	ReportCategoriesPath               string
 }
 
 func Load() Config {
@@ -26,7 +68,9 @@ func Load() Config {
 	return Config{
 		DiscordToken:                       os.Getenv("DISCORD_TOKEN"),
 		WSAddr:                             envDefault("WS_ADDR", ":8080"),
+		DBDriver:                           envDefault("DB_DRIVER", "sqlite"),
 		DBPath:                             envDefault("DB_PATH", "./database.db"),
+		DatabaseURL:                        os.Getenv("DATABASE_URL"),
 		DiscordWebhookURL:                  os.Getenv("DISCORD_WEBHOOK_URL"),
 		BlacklistPath:                      envDefault("BLACKLIST_PATH", "./blacklist.txt"),
 		GuildID:                            os.Getenv("GUILD_ID"),
@@ -35,9 +79,58 @@ func Load() Config {
 		WhitelistRequestsChannelID:         os.Getenv("WHITELIST_REQUESTS_CHANNEL_ID"),
 		MinecraftDiscordMessengerChannelID: os.Getenv("MinecraftDiscordMessengerChannelID"),
 		ServerStatusChannelID:              os.Getenv("ServerStatusChannelID"),
+		StaffChannelID:                     os.Getenv("STAFF_CHANNEL_ID"),
+		ReconcileInterval:                  envDuration("RECONCILE_INTERVAL", 0),
+		BackupDir:                          envDefault("BACKUP_DIR", "./backups"),
+		BackupInterval:                     envDuration("BACKUP_INTERVAL", 24*time.Hour),
+		BackupKeep:                         envInt("BACKUP_KEEP", 7),
+		ApplicationFormPath:                envDefault("APPLICATION_FORM_PATH", "./application_form.json"),
+		PolicyMinAge:                       envInt("POLICY_MIN_AGE", 0),
+		PolicyMinAgeAction:                 envDefault("POLICY_MIN_AGE_ACTION", "reject"),
+		PolicyAgeQuestion:                  envDefault("POLICY_AGE_QUESTION", "age"),
+		PolicyMinAccountAge:                envDuration("POLICY_MIN_ACCOUNT_AGE", 0),
+		PolicyAccountAgeAction:             envDefault("POLICY_ACCOUNT_AGE_ACTION", "flag"),
+		PolicyMinMembership:                envDuration("POLICY_MIN_MEMBERSHIP", 0),
+		PolicyMembershipAction:             envDefault("POLICY_MEMBERSHIP_ACTION", "flag"),
+		PolicyUsernameAction:               envDefault("POLICY_USERNAME_ACTION", "reject"),
+		PolicyWhitelistedUUIDAction:        envDefault("POLICY_WHITELISTED_UUID_ACTION", "flag"),
+		PolicyReapplyCooldown:              envDuration("POLICY_REAPPLY_COOLDOWN", 72*time.Hour),
+		PolicyReapplyAction:                envDefault("POLICY_REAPPLY_ACTION", "reject"),
+		WhitelistApprovalsRequired:         envInt("WHITELIST_APPROVALS_REQUIRED", 1),
+		WhitelistRejectionsRequired:        envInt("WHITELIST_REJECTIONS_REQUIRED", 1),
+		WhitelistVetoRoleID:                os.Getenv("WHITELIST_VETO_ROLE_ID"),
+		InterviewChannelID:                 os.Getenv("INTERVIEW_CHANNEL_ID"),
+		RejectionReasons: envList("REJECTION_REASONS", []string{
+			"Answers were too short or low effort",
+			"Does not meet the minimum age",
+			"Minecraft username is not allowed",
+			"Answers were incomplete or inaccurate",
+		}),
+		EscalationChannelID:       envDefault("ESCALATION_CHANNEL_ID", os.Getenv("STAFF_CHANNEL_ID")),
+		EscalationRoleID:          os.Getenv("ESCALATION_ROLE_ID"),
+		EscalationUserIDs:         envList("ESCALATION_USER_IDS", nil),
+		RulesPath:                 envDefault("RULES_PATH", "./rules.md"),
+		RulesRoleID:               os.Getenv("RULES_ROLE_ID"),
+		ApplicationRemindAfter:    envDuration("APPLICATION_REMIND_AFTER", 48*time.Hour),
+		ApplicationExpireAfter:    envDuration("APPLICATION_EXPIRE_AFTER", 7*24*time.Hour),
+		ApplicationCheckInterval:  envDuration("APPLICATION_CHECK_INTERVAL", time.Hour),
+		ApplicationReminderRoleID: os.Getenv("APPLICATION_REMINDER_ROLE_ID"),
+		PunishmentCheckInterval:   envDuration("PUNISHMENT_CHECK_INTERVAL", time.Minute),
+		InfractionWeights:         envList("INFRACTION_WEIGHTS", []string{"blacklist_minecraft=2", "blacklist_discord=1"}),
+		InfractionDecay:           envDuration("INFRACTION_DECAY", 30*24*time.Hour),
+		InfractionLadder:          envList("INFRACTION_LADDER", []string{"3=warn", "6=mute:1h", "10=tempban:3d", "15=ban"}),
+		ReportCategoriesPath:      envDefault("REPORT_CATEGORIES_PATH", "./report_categories.json"),
 	}
 }
 
+// DBDSN returns the connection string for the configured DB_DRIVER.
+func (c Config) DBDSN() string {
+	if d, _ := sqldb.ParseDialect(c.DBDriver); d == sqldb.Postgres {
+		return c.DatabaseURL
+	}
+	return c.DBPath
+}
+
 func envDefault(key, def string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -46,6 +139,47 @@ func envDefault(key, def string) string {
 	return v
 }
 
+// envList splits a semicolon separated value, e.g. "a;b;c".
+func envList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var out []string
+	for _, p := range strings.Split(v, ";") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		logging.L().Warn("ENV: invalid integer, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logging.L().Warn("ENV: invalid duration, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return d
+}
+
 func loadDotEnv() {
 	path := os.Getenv("ENV_FILE")
 	if path != "" {
@@ -0,0 +1,144 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// DB wraps *sql.DB so stores can write queries once with `?` placeholders
+// and run them on either backend.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// ParseDialect maps a DB_DRIVER value to its dialect. Empty means SQLite;
+// "postgresql" and "pgx" are accepted for Postgres, in any case.
+func ParseDialect(driver string) (Dialect, error) {
+	switch Dialect(strings.ToLower(strings.TrimSpace(driver))) {
+	case SQLite, "":
+		return SQLite, nil
+	case Postgres, "postgresql", "pgx":
+		return Postgres, nil
+	default:
+		return "", fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+func Open(driver, dsn string) (*DB, error) {
+	d, err := ParseDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	if d == Postgres {
+		return openPostgres(dsn)
+	}
+	return openSQLite(dsn)
+}
+
+// Rebind rewrites `?` placeholders to `$n` for Postgres.
+func (d *DB) Rebind(q string) string {
+	return rebind(d.Dialect, q)
+}
+
+// DDL expands dialect specific tokens in a schema statement.
+// {{pk}} becomes an auto-incrementing integer primary key.
+func (d *DB) DDL(q string) string {
+	pk := "INTEGER PRIMARY KEY AUTOINCREMENT"
+	if d.Dialect == Postgres {
+		pk = "BIGSERIAL PRIMARY KEY"
+	}
+	return strings.ReplaceAll(q, "{{pk}}", pk)
+}
+
+// Migrate runs schema statements in order.
+func (d *DB) Migrate(ctx context.Context, stmts ...string) error {
+	for _, s := range stmts {
+		if _, err := d.DB.ExecContext(ctx, d.DDL(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddColumn adds a column to an existing table unless it is already there.
+// def is the column definition after the name, e.g. "BIGINT NOT NULL DEFAULT 0".
+func (d *DB) AddColumn(ctx context.Context, table, column, def string) error {
+	var n int
+	var err error
+	if d.Dialect == Postgres {
+		err = d.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema=current_schema() AND table_name=? AND column_name=?`, table, column).Scan(&n)
+	} else {
+		err = d.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?`, table, column).Scan(&n)
+	}
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = d.DB.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, d.DDL(def)))
+	return err
+}
+
+func (d *DB) ExecContext(ctx context.Context, q string, args ...any) (sql.Result, error) {
+	return d.DB.ExecContext(ctx, d.Rebind(q), args...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, q string, args ...any) (*sql.Rows, error) {
+	return d.DB.QueryContext(ctx, d.Rebind(q), args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, q string, args ...any) *sql.Row {
+	return d.DB.QueryRowContext(ctx, d.Rebind(q), args...)
+}
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: d.Dialect}, nil
+}
+
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+func (t *Tx) ExecContext(ctx context.Context, q string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, rebind(t.dialect, q), args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, q string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, rebind(t.dialect, q), args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, q string, args ...any) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, rebind(t.dialect, q), args...)
+}
+
+func rebind(d Dialect, q string) string {
+	if d != Postgres || !strings.Contains(q, "?") {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package sqldb
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{`PRAGMA journal_mode=WAL`, `PRAGMA busy_timeout = 5000`} {
+		if _, err := db.Exec(pragma); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return &DB{DB: db, Dialect: SQLite}, nil
+}
@@ -0,0 +1,25 @@
+package whitelist
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrDiscordLinked = errors.New("discord account already linked")
+	ErrUUIDLinked    = errors.New("minecraft uuid already linked")
+	ErrUsernameTaken = errors.New("minecraft username already taken")
+)
+
+// ConflictError is returned by Add when a unique column is already in use.
+// It wraps one of the Err* sentinels and carries the row that holds it.
+type ConflictError struct {
+	Err   error
+	Entry *Entry
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v (discord %s, uuid %s, username %s)", e.Err, e.Entry.DiscordID, e.Entry.MinecraftUUID, e.Entry.Username)
+}
+
+func (e *ConflictError) Unwrap() error { return e.Err }
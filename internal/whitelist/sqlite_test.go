@@ -0,0 +1,20 @@
+package whitelist_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist/storetest"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) whitelist.Store {
+		s, err := whitelist.Open(filepath.Join(t.TempDir(), "whitelist.db"))
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	})
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 
-	_ "modernc.org/sqlite"
+	"github.com/rotaria-smp/rotaria-bot/internal/shared/sqldb"
 )
 
 type Entry struct {
@@ -14,62 +15,114 @@ type Entry struct {
 	Username      string
 	MinecraftUUID string
 }
-type Store struct {
-	db *sql.DB
+
+// Store is the whitelist persistence the Discord app depends on.
+type Store interface {
+	Add(ctx context.Context, discordID, minecraftUUID, username string) error
+	UpdateUUID(ctx context.Context, discordID, minecraftUUID string) error
+	GetByUUID(ctx context.Context, uuid string) (*Entry, error)
+	UpdateUsernameByUUID(ctx context.Context, uuid, username string) error
+	GetByUsername(ctx context.Context, username string) (*Entry, error)
+	UpdateUsername(ctx context.Context, discordID, username string) error
+	UpdateUser(ctx context.Context, discordID, minecraftUUID, username string) error
+	GetByDiscord(ctx context.Context, discordID string) (*Entry, error)
+	Remove(ctx context.Context, discordID string) error
+	TransferDiscord(ctx context.Context, minecraftUUID, newDiscordID string) error
+	List(ctx context.Context) ([]Entry, error)
 }
 
-func Open(path string) (*Store, error) {
-	db, err := sql.Open("sqlite", path)
-	if err != nil {
+// SQLStore implements Store on top of SQLite or PostgreSQL.
+type SQLStore struct {
+	db *sqldb.DB
+}
+
+// New creates the whitelist table if needed and returns a store backed by db.
+func New(db *sqldb.DB) (*SQLStore, error) {
+	if err := db.Migrate(context.Background(), `CREATE TABLE IF NOT EXISTS whitelist (
+        id {{pk}},
+        discord_id TEXT NOT NULL UNIQUE,
+        minecraft_uuid TEXT NOT NULL UNIQUE,
+        username TEXT NOT NULL UNIQUE
+    )`); err != nil {
 		return nil, err
 	}
+	return &SQLStore{db: db}, nil
+}
 
-	db.SetMaxOpenConns(1)
+// Attach returns a store on db without creating the schema, for tools that
+// must not write, e.g. a dry run.
+func Attach(db *sqldb.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
 
-	if _, err := db.Exec(`PRAGMA journal_mode=WAL`); err != nil {
+// Open opens a SQLite database at path and returns a store on it.
+func Open(path string) (*SQLStore, error) {
+	db, err := sqldb.Open(string(sqldb.SQLite), path)
+	if err != nil {
 		return nil, err
 	}
-	if _, err := db.Exec(`PRAGMA busy_timeout = 5000`); err != nil {
+	s, err := New(db)
+	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
+	return s, nil
+}
 
-	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS whitelist (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        discord_id TEXT NOT NULL UNIQUE,
-				minecraft_uuid TEXT NOT NULL UNIQUE,
-        username TEXT NOT NULL UNIQUE
-    )`); err != nil {
-		return nil, err
-	}
-	return &Store{db: db}, nil
+// Close closes the underlying database. Stores from New and Attach share
+// their caller's handle, so only close those that came from Open.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
 }
 
-func (s *Store) Add(ctx context.Context, discordID, minecraft_uuid, username string) error {
-	_, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO whitelist(discord_id,minecraft_uuid,username) VALUES(?,?,?)`, discordID, minecraft_uuid, username)
-	return err
+// Add links discordID to a Minecraft account. Adding an identical row again
+// is a no-op; any other clash returns a *ConflictError.
+func (s *SQLStore) Add(ctx context.Context, discordID, minecraft_uuid, username string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	checks := []struct {
+		q    string
+		arg  string
+		kind error
+	}{
+		{`SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE discord_id=?`, discordID, ErrDiscordLinked},
+		{`SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE minecraft_uuid=?`, minecraft_uuid, ErrUUIDLinked},
+		{`SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE username=?`, username, ErrUsernameTaken},
+	}
+	for _, c := range checks {
+		e, err := scanEntry(tx.QueryRowContext(ctx, c.q, c.arg))
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			continue
+		}
+		if e.DiscordID == discordID && e.MinecraftUUID == minecraft_uuid && e.Username == username {
+			return nil
+		}
+		return &ConflictError{Err: c.kind, Entry: e}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO whitelist(discord_id,minecraft_uuid,username) VALUES(?,?,?)`, discordID, minecraft_uuid, username); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *Store) UpdateUUID(ctx context.Context, discordID string, minecraft_uuid string) error {
+func (s *SQLStore) UpdateUUID(ctx context.Context, discordID string, minecraft_uuid string) error {
 	_, err := s.db.ExecContext(ctx, `UPDATE whitelist SET minecraft_uuid=? WHERE discord_id=?`, minecraft_uuid, discordID)
 	return err
 }
 
-func (s *Store) GetByUUID(ctx context.Context, uuid string) (*Entry, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE minecraft_uuid=?`,
-		uuid,
-	)
-	var e Entry
-	if err := row.Scan(&e.ID, &e.DiscordID, &e.MinecraftUUID, &e.Username); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &e, nil
+func (s *SQLStore) GetByUUID(ctx context.Context, uuid string) (*Entry, error) {
+	return s.getOne(ctx, `SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE minecraft_uuid=?`, uuid)
 }
 
-func (s *Store) UpdateUsernameByUUID(ctx context.Context, uuid, username string) error {
+func (s *SQLStore) UpdateUsernameByUUID(ctx context.Context, uuid, username string) error {
 	_, err := s.db.ExecContext(ctx,
 		`UPDATE whitelist SET username=? WHERE minecraft_uuid=?`,
 		username, uuid,
@@ -77,53 +130,30 @@ func (s *Store) UpdateUsernameByUUID(ctx context.Context, uuid, username string)
 	return err
 }
 
-func (s *Store) GetByUsername(ctx context.Context, username string) (*Entry, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE username=?`,
-		username,
-	)
-	var e Entry
-	if err := row.Scan(&e.ID, &e.DiscordID, &e.MinecraftUUID, &e.Username); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &e, nil
+func (s *SQLStore) GetByUsername(ctx context.Context, username string) (*Entry, error) {
+	return s.getOne(ctx, `SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE username=?`, username)
 }
 
-func (s *Store) UpdateUsername(ctx context.Context, discordID, username string) error {
+func (s *SQLStore) UpdateUsername(ctx context.Context, discordID, username string) error {
 	_, err := s.db.ExecContext(ctx, `UPDATE whitelist SET username=? WHERE discord_id=?`, username, discordID)
 	return err
 }
 
-func (s *Store) UpdateUser(ctx context.Context, discordID, minecraft_UUID, username string) error {
+func (s *SQLStore) UpdateUser(ctx context.Context, discordID, minecraft_UUID, username string) error {
 	_, err := s.db.ExecContext(ctx, `UPDATE whitelist SET minecraft_uuid=?, username=? WHERE discord_id=?`, minecraft_UUID, username, discordID)
 	return err
 }
 
-func (s *Store) GetByDiscord(ctx context.Context, discordID string) (*Entry, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE discord_id=?`,
-		discordID,
-	)
-
-	var e Entry
-	if err := row.Scan(&e.ID, &e.DiscordID, &e.MinecraftUUID, &e.Username); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &e, nil
+func (s *SQLStore) GetByDiscord(ctx context.Context, discordID string) (*Entry, error) {
+	return s.getOne(ctx, `SELECT id, discord_id, minecraft_uuid, username FROM whitelist WHERE discord_id=?`, discordID)
 }
 
-func (s *Store) Remove(ctx context.Context, discordID string) error {
+func (s *SQLStore) Remove(ctx context.Context, discordID string) error {
 	_, err := s.db.ExecContext(ctx, `DELETE FROM whitelist WHERE discord_id=?`, discordID)
 	return err
 }
 
-func (s *Store) TransferDiscord(ctx context.Context, minecraftUUID, newDiscordID string) error {
+func (s *SQLStore) TransferDiscord(ctx context.Context, minecraftUUID, newDiscordID string) error {
 	// Ensure target row exists
 	row := s.db.QueryRowContext(ctx,
 		`SELECT id FROM whitelist WHERE minecraft_uuid=?`,
@@ -143,3 +173,42 @@ func (s *Store) TransferDiscord(ctx context.Context, minecraftUUID, newDiscordID
 	)
 	return err
 }
+
+func (s *SQLStore) List(ctx context.Context) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, discord_id, minecraft_uuid, username FROM whitelist ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.DiscordID, &e.MinecraftUUID, &e.Username); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// NormalizeUUID strips hyphens and lowercases so UUIDs from the server,
+// Mojang and the database compare equal.
+func NormalizeUUID(uuid string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(uuid), "-", ""))
+}
+
+func (s *SQLStore) getOne(ctx context.Context, q string, arg any) (*Entry, error) {
+	return scanEntry(s.db.QueryRowContext(ctx, q, arg))
+}
+
+func scanEntry(row *sql.Row) (*Entry, error) {
+	var e Entry
+	if err := row.Scan(&e.ID, &e.DiscordID, &e.MinecraftUUID, &e.Username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,179 @@
+// Package storetest is a conformance suite for whitelist.Store
+// implementations. Backends call Run from their own tests with a factory
+// that returns an empty store, e.g. a temp-file SQLite database or a
+// throwaway Postgres schema.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rotaria-smp/rotaria-bot/internal/whitelist"
+)
+
+// Factory returns a fresh, empty store. Cleanup should be registered on t.
+type Factory func(t *testing.T) whitelist.Store
+
+func Run(t *testing.T, newStore Factory) {
+	t.Run("AddAndGet", func(t *testing.T) { testAddAndGet(t, newStore(t)) })
+	t.Run("GetMissing", func(t *testing.T) { testGetMissing(t, newStore(t)) })
+	t.Run("AddConflicts", func(t *testing.T) { testAddConflicts(t, newStore(t)) })
+	t.Run("Updates", func(t *testing.T) { testUpdates(t, newStore(t)) })
+	t.Run("Remove", func(t *testing.T) { testRemove(t, newStore(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newStore(t)) })
+	t.Run("TransferDiscord", func(t *testing.T) { testTransferDiscord(t, newStore(t)) })
+}
+
+func testAddAndGet(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+
+	for name, get := range map[string]func() (*whitelist.Entry, error){
+		"discord":  func() (*whitelist.Entry, error) { return s.GetByDiscord(ctx, "100") },
+		"uuid":     func() (*whitelist.Entry, error) { return s.GetByUUID(ctx, "uuid-a") },
+		"username": func() (*whitelist.Entry, error) { return s.GetByUsername(ctx, "Alice") },
+	} {
+		e, err := get()
+		if err != nil {
+			t.Fatalf("get by %s: %v", name, err)
+		}
+		expectEntry(t, e, "100", "uuid-a", "Alice")
+	}
+}
+
+func testGetMissing(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	if e, err := s.GetByDiscord(ctx, "nope"); err != nil || e != nil {
+		t.Fatalf("GetByDiscord missing = %v, %v; want nil, nil", e, err)
+	}
+	if e, err := s.GetByUUID(ctx, "nope"); err != nil || e != nil {
+		t.Fatalf("GetByUUID missing = %v, %v; want nil, nil", e, err)
+	}
+	if e, err := s.GetByUsername(ctx, "nope"); err != nil || e != nil {
+		t.Fatalf("GetByUsername missing = %v, %v; want nil, nil", e, err)
+	}
+}
+
+func testAddConflicts(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+
+	cases := []struct {
+		name                      string
+		discordID, uuid, username string
+		want                      error
+	}{
+		{"discord", "100", "uuid-b", "Bob", whitelist.ErrDiscordLinked},
+		{"uuid", "200", "uuid-a", "Bob", whitelist.ErrUUIDLinked},
+		{"username", "200", "uuid-b", "Alice", whitelist.ErrUsernameTaken},
+	}
+	for _, c := range cases {
+		err := s.Add(ctx, c.discordID, c.uuid, c.username)
+		if !errors.Is(err, c.want) {
+			t.Fatalf("%s: Add err = %v; want %v", c.name, err, c.want)
+		}
+		var ce *whitelist.ConflictError
+		if !errors.As(err, &ce) {
+			t.Fatalf("%s: Add err = %T; want *whitelist.ConflictError", c.name, err)
+		}
+		expectEntry(t, ce.Entry, "100", "uuid-a", "Alice")
+	}
+
+	if e, _ := s.GetByDiscord(ctx, "200"); e != nil {
+		t.Fatalf("conflicting Add inserted a row: %+v", e)
+	}
+}
+
+func testUpdates(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+
+	if err := s.UpdateUsername(ctx, "100", "Alice2"); err != nil {
+		t.Fatalf("UpdateUsername: %v", err)
+	}
+	e, _ := s.GetByDiscord(ctx, "100")
+	expectEntry(t, e, "100", "uuid-a", "Alice2")
+
+	if err := s.UpdateUsernameByUUID(ctx, "uuid-a", "Alice3"); err != nil {
+		t.Fatalf("UpdateUsernameByUUID: %v", err)
+	}
+	e, _ = s.GetByDiscord(ctx, "100")
+	expectEntry(t, e, "100", "uuid-a", "Alice3")
+
+	if err := s.UpdateUUID(ctx, "100", "uuid-b"); err != nil {
+		t.Fatalf("UpdateUUID: %v", err)
+	}
+	e, _ = s.GetByDiscord(ctx, "100")
+	expectEntry(t, e, "100", "uuid-b", "Alice3")
+
+	if err := s.UpdateUser(ctx, "100", "uuid-c", "Alice4"); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	e, _ = s.GetByDiscord(ctx, "100")
+	expectEntry(t, e, "100", "uuid-c", "Alice4")
+}
+
+func testRemove(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+	if err := s.Remove(ctx, "100"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if e, err := s.GetByDiscord(ctx, "100"); err != nil || e != nil {
+		t.Fatalf("after Remove = %v, %v; want nil, nil", e, err)
+	}
+	if err := s.Remove(ctx, "100"); err != nil {
+		t.Fatalf("Remove missing row: %v", err)
+	}
+}
+
+func testList(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	if got, err := s.List(ctx); err != nil || len(got) != 0 {
+		t.Fatalf("List empty = %v, %v; want none", got, err)
+	}
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+	mustAdd(t, s, "200", "uuid-b", "Bob")
+	got, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List len = %d; want 2", len(got))
+	}
+	expectEntry(t, &got[0], "100", "uuid-a", "Alice")
+	expectEntry(t, &got[1], "200", "uuid-b", "Bob")
+}
+
+func testTransferDiscord(t *testing.T, s whitelist.Store) {
+	ctx := context.Background()
+	mustAdd(t, s, "100", "uuid-a", "Alice")
+	if err := s.TransferDiscord(ctx, "uuid-a", "200"); err != nil {
+		t.Fatalf("TransferDiscord: %v", err)
+	}
+	e, _ := s.GetByUUID(ctx, "uuid-a")
+	expectEntry(t, e, "200", "uuid-a", "Alice")
+
+	if err := s.TransferDiscord(ctx, "uuid-missing", "300"); err == nil {
+		t.Fatalf("TransferDiscord on missing uuid: want error")
+	}
+}
+
+func mustAdd(t *testing.T, s whitelist.Store, discordID, uuid, username string) {
+	t.Helper()
+	if err := s.Add(context.Background(), discordID, uuid, username); err != nil {
+		t.Fatalf("Add(%s, %s, %s): %v", discordID, uuid, username, err)
+	}
+}
+
+func expectEntry(t *testing.T, e *whitelist.Entry, discordID, uuid, username string) {
+	t.Helper()
+	if e == nil {
+		t.Fatalf("entry = nil; want %s/%s/%s", discordID, uuid, username)
+	}
+	if e.DiscordID != discordID || e.MinecraftUUID != uuid || e.Username != username {
+		t.Fatalf("entry = %s/%s/%s; want %s/%s/%s", e.DiscordID, e.MinecraftUUID, e.Username, discordID, uuid, username)
+	}
+}
@@ -0,0 +1,15 @@
+package whitelist
+
+import "regexp"
+
+// UsernamePattern matches a Minecraft username.
+const UsernamePattern = `[A-Za-z0-9_]{3,16}`
+
+var usernameRe = regexp.MustCompile(`^` + UsernamePattern + `$`)
+
+// ValidUsername reports whether name is a Minecraft username. Only such
+// names may be put into a server command: a selector such as "@a" would hit
+// every online player and spaces would shift the command's arguments.
+func ValidUsername(name string) bool {
+	return usernameRe.MatchString(name)
+}
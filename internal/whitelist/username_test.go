@@ -0,0 +1,21 @@
+package whitelist
+
+import "testing"
+
+func TestValidUsername(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Alice":             true,
+		"a_b_1":             true,
+		"abc":               true,
+		"ab":                false,
+		"@a":                false,
+		"Alice op Bob":      false,
+		"Alice;stop":        false,
+		"seventeenchars17":  true,
+		"seventeenchars_17": false,
+	} {
+		if got := ValidUsername(name); got != want {
+			t.Errorf("ValidUsername(%q) = %v, want %v", name, got, want)
+		}
+	}
+}